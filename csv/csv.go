@@ -4,11 +4,14 @@ Package csv contains collection of scripts to process CSV data.
 package csv
 
 import (
+	"context"
 	"encoding/csv"
+	"errors"
 	"io"
 	"log"
 	"os"
-	"sync"
+
+	"github.com/keenangebze/go/pipeline"
 )
 
 // ProcessCSVFileByRow is a wrapper of processCSVByRow that reads CSV file and output the processed CSV as a file.
@@ -47,11 +50,23 @@ func ProcessCSVFileByRowParallel(inputCSV string, outputCSV string, rowProcessor
 	ProcessCSVByRowParallel(inFile, outFile, rowProcessor, skipHeader)
 }
 
+// RowWriter is the output sink of the processed rows, e.g. *csv.Writer or *parquet.Writer.
+type RowWriter interface {
+	Write(row []string) error
+}
+
 // ProcessCSVByRow reads csv row line by line, then do rowProcessor() on each row and output a new row.
 func ProcessCSVByRow(in io.Reader, out io.Writer, rowProcessor func([]string) []string, skipHeader bool) {
-	// Read the input and output file as CSV
+	outCSVWriter := csv.NewWriter(out)
+	ProcessCSVByRowTo(in, outCSVWriter, rowProcessor, skipHeader)
+	outCSVWriter.Flush()
+}
+
+// ProcessCSVByRowTo is like ProcessCSVByRow but the processed rows are written to out, e.g. a parquet.Writer.
+// The caller is responsible to flush or close out.
+func ProcessCSVByRowTo(in io.Reader, out RowWriter, rowProcessor func([]string) []string, skipHeader bool) {
+	// Read the input file as CSV
 	inCSVReader := csv.NewReader(in)
-	outCSVReader := csv.NewWriter(out)
 
 	if skipHeader {
 		inCSVReader.Read()
@@ -73,61 +88,74 @@ func ProcessCSVByRow(in io.Reader, out io.Writer, rowProcessor func([]string) []
 		if result == nil {
 			continue
 		}
-		err = outCSVReader.Write(result)
+		err = out.Write(result)
 		if err != nil {
 			log.Printf("Cannot write row at line %v.\n", lineCount)
 			continue
 		}
 	}
-	outCSVReader.Flush()
 }
 
 // ProcessCSVByRowParallel process the CSV row by row in parallel.
 // This is much faster than its processCSVByRow but doesn't maintain row ordering.
 func ProcessCSVByRowParallel(in io.Reader, out io.Writer, rowProcessor func([]string) []string, skipHeader bool) {
-	// Read the input and output file as CSV
-	inCSVReader := csv.NewReader(in)
 	outCSVWriter := csv.NewWriter(out)
+	ProcessCSVByRowParallelTo(in, outCSVWriter, rowProcessor, skipHeader)
+	outCSVWriter.Flush()
+}
 
-	workerPool := NewRowWorkerPool(rowProcessor)
-
-	// Create the writer goroutine
-	var wg sync.WaitGroup
-	wg.Add(1)
-	// Writer
-	go func() {
-		for processed := range workerPool.Consume() {
-			if processed != nil {
-				outCSVWriter.Write(processed)
-			}
-		}
-		outCSVWriter.Flush()
-		wg.Done()
-	}()
+// ProcessCSVByRowParallelTo is like ProcessCSVByRowParallel but the processed rows are written to out, e.g. a parquet.Writer.
+// The caller is responsible to flush or close out.
+func ProcessCSVByRowParallelTo(in io.Reader, out RowWriter, rowProcessor func([]string) []string, skipHeader bool) {
+	err := ProcessCSV(context.Background(), in, out, rowProcessor, skipHeader, pipeline.Options{
+		Workers: NumberOfGoroutines,
+		OnError: func(err error) error {
+			log.Printf("Cannot write row %v.\n", err)
+			return nil
+		},
+	})
+	if err != nil {
+		log.Printf("Cannot process CSV: %v\n", err)
+	}
+}
 
+// ProcessCSV process the CSV row by row with the pipeline options,
+// e.g. opts.Ordered to maintain row ordering or cancel ctx to stop early.
+// Rows that cannot be read are logged and skipped, the rows out rejects are handled by opts.OnError.
+// It returns the first error from out or ctx.
+func ProcessCSV(ctx context.Context, in io.Reader, out RowWriter, rowProcessor func([]string) []string, skipHeader bool, opts pipeline.Options) error {
+	inCSVReader := csv.NewReader(in)
 	if skipHeader {
 		inCSVReader.Read()
 	}
+	sink := pipeline.SinkFunc[[]string](func(ctx context.Context, row []string) error {
+		return out.Write(row)
+	})
+	return pipeline.Run[[]string, []string](ctx, NewRowSource(inCSVReader), rowStage(rowProcessor), sink, opts)
+}
 
-	// Feed the CSV row to the Row Worker pool
+// NewRowSource reads the CSV rows as a pipeline.Source. Malformed rows are logged and skipped.
+func NewRowSource(reader *csv.Reader) pipeline.Source[[]string] {
 	lineCount := 0
-	for {
-		row, err := inCSVReader.Read()
-		if err == io.EOF {
-			break
-		}
-		lineCount++
-		if err != nil {
-			log.Printf("Cannot read row at line %v.\n", lineCount)
-			continue
+	return pipeline.SourceFunc[[]string](func(ctx context.Context) ([]string, error) {
+		for {
+			row, err := reader.Read()
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			lineCount++
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				log.Printf("Cannot read row at line %v.\n", lineCount)
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			// protect against shared row reference
+			copiedRow := make([]string, len(row), len(row))
+			copy(copiedRow, row)
+			return copiedRow, nil
 		}
-		// protect against shared row reference
-		copiedRow := make([]string, len(row), len(row))
-		copy(copiedRow, row)
-
-		// feed in to the worker pool
-		workerPool.Feed(copiedRow)
-	}
-	workerPool.Close()
-	wg.Wait()
+	})
 }
@@ -1,9 +1,12 @@
 package csv
 
 import (
+	"context"
 	"errors"
 	"runtime"
 	"sync"
+
+	"github.com/keenangebze/go/pipeline"
 )
 
 // Set the number of goroutines in the pool
@@ -13,11 +16,13 @@ var NumberOfGoroutines = runtime.NumCPU() * 32
 // It is heavily inspired by Jason Waldrip's code from in the book "Go in Action" (2015)
 // by William Kenedy with Brian Ketelsen and Erik St. Martin.
 // https://learning.oreilly.com/library/view/go-in-action/9781617291784/kindle_split_015.html
+//
+// The rows are processed by a pipeline.Run, fed from and consumed through channels.
 type RowWorkerPool struct {
-	process   func(row []string) []string
 	inStream  chan []string
 	outStream chan []string
-	wg        sync.WaitGroup
+	done      chan struct{}
+	mu        sync.RWMutex
 	closed    bool
 }
 
@@ -27,28 +32,28 @@ var ErrWorkerClosed = errors.New("worker pool already closed")
 // NewRowWorkerPool instantiate the worker pool.
 func NewRowWorkerPool(rowProcessor func(row []string) []string) *RowWorkerPool {
 	pool := RowWorkerPool{
-		process:   rowProcessor,
 		inStream:  make(chan []string),
 		outStream: make(chan []string),
+		done:      make(chan struct{}),
 	}
-	pool.wg.Add(int(NumberOfGoroutines))
-	for i := 0; i < int(NumberOfGoroutines); i++ {
-		go func() {
-			for task := range pool.inStream {
-				result := pool.process(task)
-				if result == nil {
-					continue
-				}
-				pool.outStream <- result
-			}
-			pool.wg.Done()
-		}()
-	}
+	go func() {
+		pipeline.Run[[]string, []string](
+			context.Background(),
+			pipeline.FromChan(pool.inStream),
+			rowStage(rowProcessor),
+			pipeline.ToChan(pool.outStream),
+			pipeline.Options{Workers: NumberOfGoroutines},
+		)
+		close(pool.outStream)
+		close(pool.done)
+	}()
 	return &pool
 }
 
 // Feed feeds the worker pool with CSV's row.
 func (rw *RowWorkerPool) Feed(row []string) error {
+	rw.mu.RLock()
+	defer rw.mu.RUnlock()
 	if rw.closed {
 		return ErrWorkerClosed
 	}
@@ -61,9 +66,25 @@ func (rw *RowWorkerPool) Consume() <-chan []string {
 	return rw.outStream
 }
 
-// Close closes the worker pool.
+// Close closes the worker pool and waits until every fed row is processed.
+// The processed rows must still be consumed for Close to return.
 func (rw *RowWorkerPool) Close() {
-	close(rw.inStream)
-	rw.wg.Wait()
-	close(rw.outStream)
+	rw.mu.Lock()
+	if !rw.closed {
+		rw.closed = true
+		close(rw.inStream)
+	}
+	rw.mu.Unlock()
+	<-rw.done
+}
+
+// rowStage adapts the row processor in to a pipeline.Stage, a nil result skips the row.
+func rowStage(rowProcessor func(row []string) []string) pipeline.Stage[[]string, []string] {
+	return pipeline.StageFunc[[]string, []string](func(ctx context.Context, row []string) ([]string, error) {
+		result := rowProcessor(row)
+		if result == nil {
+			return nil, pipeline.ErrSkip
+		}
+		return result, nil
+	})
 }
@@ -1,15 +1,29 @@
 module github.com/keenangebze/go
 
-go 1.17
+go 1.18
 
 require (
+	github.com/alicebob/miniredis/v2 v2.30.0
+	github.com/bits-and-blooms/bloom/v3 v3.0.1
 	github.com/go-redis/redis v6.15.9+incompatible
 	github.com/spf13/cobra v1.5.0
+	github.com/xitongsys/parquet-go v1.6.2
+	github.com/xitongsys/parquet-go-source v0.0.0-20200817004010-026bad9b25d0
+	golang.org/x/time v0.5.0
 )
 
 require (
+	github.com/alicebob/gopher-json v0.0.0-20200520072559-a9ecdc9d1d3a // indirect
+	github.com/apache/arrow/go/arrow v0.0.0-20200730104253-651201b0f516 // indirect
+	github.com/apache/thrift v0.14.2 // indirect
+	github.com/bits-and-blooms/bitset v1.2.0 // indirect
+	github.com/golang/snappy v0.0.3 // indirect
 	github.com/inconshreveable/mousetrap v1.0.0 // indirect
+	github.com/klauspost/compress v1.13.1 // indirect
 	github.com/onsi/ginkgo v1.16.5 // indirect
 	github.com/onsi/gomega v1.21.1 // indirect
+	github.com/pierrec/lz4/v4 v4.1.8 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
+	github.com/yuin/gopher-lua v0.0.0-20220504180219-658193537a64 // indirect
+	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
 )
@@ -3,6 +3,7 @@ package config
 var DEFAULT Config
 
 type Config struct {
-	// RateLimit how many request we will send to server
+	// RateLimit how many request per second we will send to server, 0 means unlimited.
+	// It is the default of the redis --rate-limit flag.
 	RateLimit int
 }
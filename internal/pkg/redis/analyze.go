@@ -0,0 +1,330 @@
+package redis
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	redis "github.com/go-redis/redis"
+)
+
+// AnalyzeOptions configures how the keys are aggregated by Analyze.
+type AnalyzeOptions struct {
+	// Delimiter splits the keys in to segments, e.g. ":" groups "user:1:cart" under the prefix "user".
+	// Empty groups every key under NoPrefix.
+	Delimiter string
+	// Depth is the number of segments making the prefix, e.g. 2 groups "user:1:cart" under "user:1". Default to 1.
+	Depth int
+	// MaxPrefixes is the number of prefixes reported, the keys of the later ones are grouped under OtherPrefixes.
+	// It bounds the memory of the report when the prefixes are unique, e.g. an id as the first segment. Default to 10000.
+	MaxPrefixes int
+	// SampleRate is the fraction of the scanned keys which are analyzed, between 0 and 1. Default to 1, every key.
+	SampleRate float64
+	// MaxKeys stops once that many keys are analyzed, 0 means no limit.
+	MaxKeys int64
+	// Top is the number of biggest keys reported. Default to 10.
+	Top int
+}
+
+// The fixed prefixes grouping the keys without delimiter, and the keys of the prefixes beyond AnalyzeOptions.MaxPrefixes.
+const (
+	NoPrefix      = "(none)"
+	OtherPrefixes = "(other)"
+)
+
+// Usage is the memory and cardinality of a group of keys.
+type Usage struct {
+	Keys int64 `json:"keys"`
+	// Memory is the sum of MEMORY USAGE in bytes.
+	Memory int64 `json:"memory"`
+	// Elements is the sum of the length of the values, e.g. LLEN for a list and STRLEN for a string.
+	Elements int64 `json:"elements"`
+}
+
+// KeyUsage is the memory and cardinality of a single key.
+type KeyUsage struct {
+	Key      string `json:"key"`
+	Type     string `json:"type"`
+	Memory   int64  `json:"memory"`
+	Elements int64  `json:"elements"`
+	// TTL is the time to live in seconds, -1 for no expiry.
+	TTL int64 `json:"ttl"`
+}
+
+// TTLBuckets are the time to live ranges of Report.TTL, by their upper bound. The keys without expiry are in "none".
+var TTLBuckets = []struct {
+	Name  string
+	Below time.Duration
+}{
+	{"<1h", time.Hour},
+	{"<1d", 24 * time.Hour},
+	{"<7d", 7 * 24 * time.Hour},
+	{"<30d", 30 * 24 * time.Hour},
+	{">=30d", 1<<63 - 1},
+}
+
+// Report is the memory and cardinality of a keyspace.
+type Report struct {
+	// Scanned is the number of keys scanned, of which Total.Keys are analyzed when sampling.
+	Scanned  int64             `json:"scanned"`
+	Total    Usage             `json:"total"`
+	Types    map[string]*Usage `json:"types"`
+	Prefixes map[string]*Usage `json:"prefixes"`
+	// TTL is the number of keys of each of TTLBuckets, and "none".
+	TTL map[string]int64 `json:"ttl"`
+	// Biggest are the keys using the most memory, the biggest first.
+	Biggest []KeyUsage `json:"biggest"`
+
+	top         int
+	maxPrefixes int
+}
+
+// NewReport returns an empty Report keeping the top biggest keys and at most maxPrefixes prefixes,
+// see AnalyzeOptions.MaxPrefixes.
+func NewReport(top, maxPrefixes int) *Report {
+	if top <= 0 {
+		top = 10
+	}
+	if maxPrefixes <= 0 {
+		maxPrefixes = 10000
+	}
+	return &Report{
+		Types:       map[string]*Usage{},
+		Prefixes:    map[string]*Usage{},
+		TTL:         map[string]int64{},
+		top:         top,
+		maxPrefixes: maxPrefixes,
+	}
+}
+
+// add accounts the key under the prefix.
+func (r *Report) add(prefix string, key KeyUsage) {
+	for _, usage := range []*Usage{&r.Total, r.usage(r.Types, key.Type), r.prefix(prefix)} {
+		usage.Keys++
+		usage.Memory += key.Memory
+		usage.Elements += key.Elements
+	}
+	r.TTL[ttlBucket(key.TTL)]++
+
+	// keep the top biggest keys sorted
+	i := sort.Search(len(r.Biggest), func(i int) bool { return r.Biggest[i].Memory < key.Memory })
+	if i >= r.top {
+		return
+	}
+	if len(r.Biggest) < r.top {
+		r.Biggest = append(r.Biggest, KeyUsage{})
+	}
+	copy(r.Biggest[i+1:], r.Biggest[i:])
+	r.Biggest[i] = key
+}
+
+// Merge adds the keys of other, e.g. the report of another cluster node.
+func (r *Report) Merge(other *Report) {
+	r.Scanned += other.Scanned
+	r.Total.Keys += other.Total.Keys
+	r.Total.Memory += other.Total.Memory
+	r.Total.Elements += other.Total.Elements
+	merge := func(to, from *Usage) {
+		to.Keys += from.Keys
+		to.Memory += from.Memory
+		to.Elements += from.Elements
+	}
+	for name, from := range other.Types {
+		merge(r.usage(r.Types, name), from)
+	}
+	for _, name := range other.SortedPrefixes() {
+		merge(r.prefix(name), other.Prefixes[name])
+	}
+	for bucket, n := range other.TTL {
+		r.TTL[bucket] += n
+	}
+	biggest := append(r.Biggest, other.Biggest...)
+	sort.SliceStable(biggest, func(i, j int) bool { return biggest[i].Memory > biggest[j].Memory })
+	if len(biggest) > r.top {
+		biggest = biggest[:r.top]
+	}
+	r.Biggest = biggest
+}
+
+// SortedPrefixes returns the prefixes by descending memory.
+func (r *Report) SortedPrefixes() []string {
+	prefixes := make([]string, 0, len(r.Prefixes))
+	for prefix := range r.Prefixes {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		a, b := r.Prefixes[prefixes[i]], r.Prefixes[prefixes[j]]
+		if a.Memory != b.Memory {
+			return a.Memory > b.Memory
+		}
+		return prefixes[i] < prefixes[j]
+	})
+	return prefixes
+}
+
+func (r *Report) usage(m map[string]*Usage, name string) *Usage {
+	usage, ok := m[name]
+	if !ok {
+		usage = &Usage{}
+		m[name] = usage
+	}
+	return usage
+}
+
+// prefix returns the usage of the prefix, or of OtherPrefixes once the report has maxPrefixes prefixes.
+func (r *Report) prefix(name string) *Usage {
+	if _, ok := r.Prefixes[name]; !ok && len(r.Prefixes) >= r.maxPrefixes {
+		name = OtherPrefixes
+	}
+	return r.usage(r.Prefixes, name)
+}
+
+// ttlBucket returns the TTLBuckets name of a TTL in seconds.
+func ttlBucket(ttl int64) string {
+	if ttl < 0 {
+		return "none"
+	}
+	for _, bucket := range TTLBuckets {
+		if time.Duration(ttl)*time.Second < bucket.Below {
+			return bucket.Name
+		}
+	}
+	return TTLBuckets[len(TTLBuckets)-1].Name
+}
+
+// Prefix returns the first depth segments of the key split by delimiter, or all but the last segment of a key
+// with fewer segments, so the prefix never is a whole key. A key without delimiter is under NoPrefix.
+func Prefix(key, delimiter string, depth int) string {
+	if delimiter == "" {
+		return NoPrefix
+	}
+	if depth <= 0 {
+		depth = 1
+	}
+	segments := strings.SplitN(key, delimiter, depth+1)
+	switch {
+	case len(segments) == 1:
+		return NoPrefix
+	case len(segments) <= depth:
+		depth = len(segments) - 1
+	}
+	return strings.Join(segments[:depth], delimiter)
+}
+
+// Analyze reports the memory and cardinality of the keys selected by opts.
+//
+// For each page of keys, TYPE, MEMORY USAGE and TTL are pipelined, then the length of each key
+// with STRLEN, LLEN, SCARD, ZCARD, HLEN or XLEN depending on its type.
+// The memory is reported as 0 if MEMORY USAGE is not supported by the server.
+func (c *Client) Analyze(ctx context.Context, opts ScanOptions, analyze AnalyzeOptions) (*Report, error) {
+	report := NewReport(analyze.Top, analyze.MaxPrefixes)
+	// the global source is not seeded before go 1.20, every run would sample the same keys
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	if analyze.SampleRate <= 0 || analyze.SampleRate > 1 {
+		analyze.SampleRate = 1
+	}
+	pipe := c.redis.Pipeline()
+	defer pipe.Close()
+
+	memoryUnsupported := false
+	it := c.ScanKeys(ctx, opts)
+	for it.Next() {
+		report.Scanned += int64(len(it.Keys()))
+		keys := make([]string, 0, len(it.Keys()))
+		for _, key := range it.Keys() {
+			if analyze.MaxKeys > 0 && report.Total.Keys+int64(len(keys)) >= analyze.MaxKeys {
+				break
+			}
+			if analyze.SampleRate == 1 || random.Float64() < analyze.SampleRate {
+				keys = append(keys, key)
+			}
+		}
+
+		types := make([]*redis.StatusCmd, len(keys))
+		memories := make([]*redis.IntCmd, len(keys))
+		ttls := make([]*redis.DurationCmd, len(keys))
+		for i, key := range keys {
+			types[i] = pipe.Type(key)
+			memories[i] = pipe.MemoryUsage(key)
+			ttls[i] = pipe.TTL(key)
+		}
+		if err := it.wait(3 * len(keys)); err != nil {
+			return report, err
+		}
+		if _, err := pipe.Exec(); err != nil && !isRedisError(err) {
+			return report, err
+		}
+
+		lengths := make([]*redis.IntCmd, len(keys))
+		for i, key := range keys {
+			lengths[i] = queueLength(pipe, types[i].Val(), key)
+		}
+		if err := it.wait(len(keys)); err != nil {
+			return report, err
+		}
+		if _, err := pipe.Exec(); err != nil && !isRedisError(err) {
+			return report, err
+		}
+
+		for i, key := range keys {
+			keyType, err := types[i].Result()
+			if err == nil && keyType == "none" {
+				// deleted since scanned
+				continue
+			}
+			usage := KeyUsage{Key: key, Type: keyType, TTL: -1}
+			if err == nil {
+				if usage.Memory, err = memories[i].Result(); err != nil && err != redis.Nil {
+					// e.g. MEMORY is not supported before redis 4
+					if !memoryUnsupported {
+						log.Println("[WARN] Cannot get MEMORY USAGE, the memory is reported as 0", err)
+						memoryUnsupported = true
+					}
+					err = nil
+				}
+			}
+			if err == nil && lengths[i] != nil {
+				usage.Elements, err = lengths[i].Result()
+			}
+			if err == redis.Nil {
+				continue
+			}
+			if err != nil {
+				if err := keyError(opts.OnError, key, err); err != nil {
+					return report, err
+				}
+				continue
+			}
+			if ttl := ttls[i].Val(); ttl >= 0 {
+				usage.TTL = int64(ttl / time.Second)
+			}
+			report.add(Prefix(key, analyze.Delimiter, analyze.Depth), usage)
+		}
+		if analyze.MaxKeys > 0 && report.Total.Keys >= analyze.MaxKeys {
+			break
+		}
+	}
+	return report, it.Err()
+}
+
+// queueLength queues the command returning the length of a key of the type, nil for an unknown type.
+func queueLength(pipe redis.Pipeliner, keyType, key string) *redis.IntCmd {
+	switch keyType {
+	case "string":
+		return pipe.StrLen(key)
+	case "list":
+		return pipe.LLen(key)
+	case "set":
+		return pipe.SCard(key)
+	case "zset":
+		return pipe.ZCard(key)
+	case "hash":
+		return pipe.HLen(key)
+	case "stream":
+		return pipe.XLen(key)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package redis_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+
+	"github.com/keenangebze/go/internal/pkg/redis"
+)
+
+func TestAnalyze(t *testing.T) {
+	s := miniredis.RunT(t)
+	// miniredis has no MEMORY USAGE, use the length of the key name as its memory
+	s.Server().Register("MEMORY", func(c *server.Peer, cmd string, args []string) {
+		c.WriteInt(len(args[1]))
+	})
+	s.Set("user:1", "toko")
+	s.Set("user:22", "pedia")
+	s.SetTTL("user:22", 2*time.Hour)
+	s.RPush("queue:orders", "a", "b", "c")
+	s.HSet("shop", "name", "toko")
+
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+	report, err := client.Analyze(context.Background(), redis.ScanOptions{Match: "*", Count: 2}, redis.AnalyzeOptions{Delimiter: ":", Top: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Scanned != 4 || report.Total != (redis.Usage{Keys: 4, Memory: 29, Elements: 13}) {
+		t.Errorf("Expected 4 keys of 29 bytes and 13 elements returned %v %+v.\n", report.Scanned, report.Total)
+	}
+	if u := report.Prefixes["user"]; u == nil || *u != (redis.Usage{Keys: 2, Memory: 13, Elements: 9}) {
+		t.Errorf("Expected user prefix of 2 keys returned %+v.\n", u)
+	}
+	if u := report.Types["list"]; u == nil || *u != (redis.Usage{Keys: 1, Memory: 12, Elements: 3}) {
+		t.Errorf("Expected 1 list returned %+v.\n", u)
+	}
+	if fmt.Sprint(report.TTL) != "map[<1d:1 none:3]" {
+		t.Errorf("Expected 1 key expiring within a day returned %v.\n", report.TTL)
+	}
+	if len(report.Biggest) != 2 || report.Biggest[0].Key != "queue:orders" || report.Biggest[1].Key != "user:22" {
+		t.Errorf("Expected queue:orders and user:22 as the biggest returned %+v.\n", report.Biggest)
+	}
+	if fmt.Sprint(report.SortedPrefixes()) != "[user queue (none)]" {
+		t.Errorf("Expected prefixes by memory returned %v.\n", report.SortedPrefixes())
+	}
+
+	capped, err := client.Analyze(context.Background(), redis.ScanOptions{Match: "*", Count: 10}, redis.AnalyzeOptions{Delimiter: ":", MaxPrefixes: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys int64
+	for _, u := range capped.Prefixes {
+		keys += u.Keys
+	}
+	if len(capped.Prefixes) != 2 || capped.Prefixes[redis.OtherPrefixes] == nil || keys != 4 {
+		t.Errorf("Expected 1 prefix and the others grouped returned %v.\n", capped.SortedPrefixes())
+	}
+
+	sampled, err := client.Analyze(context.Background(), redis.ScanOptions{Match: "*", Count: 10}, redis.AnalyzeOptions{MaxKeys: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sampled.Total.Keys != 3 {
+		t.Errorf("Expected 3 keys analyzed returned %v.\n", sampled.Total.Keys)
+	}
+	report.Merge(sampled)
+	if report.Total.Keys != 7 || len(report.Biggest) != 2 {
+		t.Errorf("Expected 7 keys merged returned %+v.\n", report.Total)
+	}
+}
+
+func ExamplePrefix() {
+	fmt.Println(redis.Prefix("user:1:cart", ":", 1))
+	fmt.Println(redis.Prefix("user:1:cart", ":", 2))
+	fmt.Println(redis.Prefix("user:1", ":", 2))
+	fmt.Println(redis.Prefix("session", ":", 1))
+
+	// Output:
+	// user
+	// user:1
+	// user
+	// (none)
+}
@@ -0,0 +1,116 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/keenangebze/go/pipeline"
+)
+
+// MaxFieldSize is the maximum length of a field read by a binary Source, the maximum size of a redis string.
+var MaxFieldSize uint64 = 512 * 1024 * 1024
+
+// maxRecordFields is the maximum number of fields of a record: the type, the key and the 2^32 - 1 elements of a redis value,
+// twice for the pairs of a hash or a sorted set.
+const maxRecordFields = 2 + 2*(1<<32-1)
+
+// ErrCorruptedBinary thrown if the binary input is truncated or not written by a BinarySink.
+var ErrCorruptedBinary = errors.New("corrupted binary dump")
+
+// BinarySink writes each entry as a record of length-prefixed fields:
+//
+//	uvarint(number of fields << 2 | ttl << 1 | truncated) [uvarint(TTL in milliseconds)] [uvarint(length) bytes]...
+//
+// where the fields are the type, the key and the values, truncated is 1 for Entry.Truncated,
+// and ttl is 1 when the TTL of Entry.TTL follows the header.
+// Any value, including binary ones, is read back exactly.
+type BinarySink struct {
+	keyBuffer
+}
+
+// NewBinarySink returns a BinarySink writing to out.
+func NewBinarySink(out io.Writer) *BinarySink {
+	return &BinarySink{keyBuffer: keyBuffer{out: out}}
+}
+
+// Write implements pipeline.Sink.
+func (s *BinarySink) Write(ctx context.Context, entry Entry) error {
+	if err := s.start(entry.Key); err != nil {
+		return err
+	}
+	var prefix [binary.MaxVarintLen64]byte
+	writeField := func(field string) {
+		s.buf.Write(prefix[:binary.PutUvarint(prefix[:], uint64(len(field)))])
+		s.buf.WriteString(field)
+	}
+	header := uint64(len(entry.Values)+2) << 2
+	if entry.TTL > 0 {
+		header |= 2
+	}
+	if entry.Truncated {
+		header |= 1
+	}
+	s.buf.Write(prefix[:binary.PutUvarint(prefix[:], header)])
+	if entry.TTL > 0 {
+		s.buf.Write(prefix[:binary.PutUvarint(prefix[:], uint64(entry.TTL.Milliseconds()))])
+	}
+	writeField(entry.Type)
+	writeField(entry.Key)
+	for _, value := range entry.Values {
+		writeField(value)
+	}
+	return nil
+}
+
+// Flush writes the buffered records.
+func (s *BinarySink) Flush() error {
+	return s.flush()
+}
+
+// NewBinarySource returns a Source reading the records written by a BinarySink.
+func NewBinarySource(in io.Reader) pipeline.Source[Entry] {
+	reader := bufio.NewReader(in)
+	return entrySource(func() (Entry, error) {
+		header, err := binary.ReadUvarint(reader)
+		if err == io.EOF {
+			return Entry{}, io.EOF
+		}
+		n := header >> 2
+		if err != nil || n < 2 || n > maxRecordFields {
+			return Entry{}, fmt.Errorf("%w: invalid record", ErrCorruptedBinary)
+		}
+		var ttl uint64
+		if header&2 == 2 {
+			if ttl, err = binary.ReadUvarint(reader); err != nil {
+				return Entry{}, fmt.Errorf("%w: invalid TTL", ErrCorruptedBinary)
+			}
+		}
+		// the fields are appended as read, so a corrupted header cannot allocate more than the input holds
+		fields := make([]string, 0, minUint64(n, 1024))
+		for i := uint64(0); i < n; i++ {
+			length, err := binary.ReadUvarint(reader)
+			if err != nil || length > MaxFieldSize {
+				return Entry{}, fmt.Errorf("%w: invalid field length", ErrCorruptedBinary)
+			}
+			field := make([]byte, length)
+			if _, err := io.ReadFull(reader, field); err != nil {
+				return Entry{}, fmt.Errorf("%w: truncated field", ErrCorruptedBinary)
+			}
+			fields = append(fields, string(field))
+		}
+		return Entry{Type: fields[0], Key: fields[1], Values: fields[2:], Truncated: header&1 == 1, TTL: time.Duration(ttl) * time.Millisecond}, nil
+	})
+}
+
+// minUint64 returns the smaller of a and b.
+func minUint64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,209 @@
+/*
+Package redis dumps, populates and inspects redis keyspaces.
+
+A Client is created from a Config, the keys are selected with ScanOptions and iterated with ScanKeys.
+The Dump methods fetch the value of the keys and deliver them as Entry to a pipeline.Sink,
+e.g. a CSVSink, and Populate writes the entries read from a pipeline.Source, e.g. a CSVSource, back to redis.
+*/
+package redis
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	redis "github.com/go-redis/redis"
+)
+
+// Config is the connection configuration of redis.
+type Config struct {
+	// Address is the <HOST>:<PORT> of the redis node.
+	Address string
+	// Username is the ACL user of redis 6, authenticated with AUTH <username> <password>. Empty means the default user.
+	Username string
+	Password string
+	// DB is the database selected, a cluster only has the database 0.
+	DB int
+	// Cluster treats Address as a seed node of a redis cluster, the commands are then routed to the node owning the key.
+	// Scanning is done node by node, see ClusterMasters.
+	Cluster bool
+	// TLS connects with TLS if not nil, see NewTLSConfig. The server name defaults to the host of Address,
+	// the host of the seed for every node of a cluster, or of the sentinel for its master.
+	TLS *tls.Config
+	// DialTimeout, ReadTimeout and WriteTimeout of the connections, 0 means the go-redis defaults of 5s, 3s and 3s.
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+}
+
+// ErrClusterDB thrown if a database other than 0 is selected on a cluster.
+var ErrClusterDB = errors.New("a redis cluster only has the database 0")
+
+// ParseURL returns the config of a redis URL, redis://[[username]:password@]host[:port][/db],
+// or rediss:// to connect with TLS. The port defaults to 6379.
+func ParseURL(rawURL string) (Config, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Config{}, err
+	}
+	config := Config{}
+	switch u.Scheme {
+	case "redis":
+	case "rediss":
+		config.TLS = &tls.Config{}
+	default:
+		return Config{}, fmt.Errorf("invalid redis URL scheme %q, must be redis or rediss", u.Scheme)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "6379"
+	}
+	config.Address = net.JoinHostPort(u.Hostname(), port)
+	if u.User != nil {
+		config.Username = u.User.Username()
+		config.Password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if config.DB, err = strconv.Atoi(db); err != nil {
+			return Config{}, fmt.Errorf("invalid redis URL database %q: %w", db, err)
+		}
+	}
+	return config, nil
+}
+
+// NewTLSConfig returns a TLS config trusting the PEM certificates of the caCert file in addition to the system ones,
+// and presenting the client certificate of the cert and key files. Empty files are ignored.
+// insecureSkipVerify accepts any server certificate.
+func NewTLSConfig(caCert, cert, key string, insecureSkipVerify bool) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: insecureSkipVerify}
+	if caCert != "" {
+		pem, err := os.ReadFile(caCert)
+		if err != nil {
+			return nil, err
+		}
+		if config.RootCAs, err = x509.SystemCertPool(); err != nil {
+			config.RootCAs = x509.NewCertPool()
+		}
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %v", caCert)
+		}
+	}
+	if cert != "" || key != "" {
+		certificate, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	return config, nil
+}
+
+// Client is a connection to a redis node, or a redis cluster if Config.Cluster is set.
+type Client struct {
+	config Config
+	redis  redis.UniversalClient
+}
+
+// NewClient initialize the redis client.
+// A database other than 0 on a cluster fails the commands with ErrClusterDB.
+func NewClient(config Config) *Client {
+	c := Client{config: config}
+	opts := config.options()
+	if config.Cluster {
+		if config.DB != 0 {
+			opts.OnConnect = func(conn *redis.Conn) error {
+				return ErrClusterDB
+			}
+		}
+		c.redis = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        []string{opts.Addr},
+			Password:     opts.Password,
+			OnConnect:    opts.OnConnect,
+			MaxRetries:   opts.MaxRetries,
+			DialTimeout:  opts.DialTimeout,
+			ReadTimeout:  opts.ReadTimeout,
+			WriteTimeout: opts.WriteTimeout,
+			TLSConfig:    opts.TLSConfig,
+		})
+	} else {
+		c.redis = redis.NewClient(opts)
+	}
+	return &c
+}
+
+// options returns the go-redis options of the config.
+// go-redis authenticates with the password alone, and before selecting the database,
+// so an ACL user authenticates on connect then selects the database.
+// node returns the config of a node discovered from config, e.g. a master of the cluster of a seed.
+// The TLS server name stays the host of config, as a managed redis certifies its hostname and not the node IP.
+func (config Config) node(address string) Config {
+	node := config
+	node.Address = address
+	if config.TLS != nil {
+		node.TLS = config.TLS.Clone()
+		if node.TLS.ServerName == "" {
+			node.TLS.ServerName, _, _ = net.SplitHostPort(config.Address)
+		}
+	}
+	return node
+}
+
+func (config Config) options() *redis.Options {
+	opts := &redis.Options{
+		Addr:         config.Address,
+		Password:     config.Password,
+		DB:           config.DB,
+		MaxRetries:   5,
+		DialTimeout:  config.DialTimeout,
+		ReadTimeout:  config.ReadTimeout,
+		WriteTimeout: config.WriteTimeout,
+	}
+	if config.Username != "" {
+		opts.Password, opts.DB = "", 0
+		opts.OnConnect = func(conn *redis.Conn) error {
+			if err := conn.Process(redis.NewStatusCmd("auth", config.Username, config.Password)); err != nil {
+				return err
+			}
+			if config.DB == 0 {
+				return nil
+			}
+			return conn.Select(config.DB).Err()
+		}
+	}
+	if config.TLS != nil {
+		opts.TLSConfig = config.TLS.Clone()
+		if opts.TLSConfig.ServerName == "" {
+			opts.TLSConfig.ServerName, _, _ = net.SplitHostPort(config.Address)
+		}
+	}
+	return opts
+}
+
+// Close closes the connections.
+func (c *Client) Close() error {
+	return c.redis.Close()
+}
+
+// Config returns the configuration of the client.
+func (c *Client) Config() Config {
+	return c.config
+}
+
+// keyError annotates the error of a single key, and hands it to onError.
+// The default onError logs and skips the key.
+func keyError(onError func(err error) error, key string, err error) error {
+	err = fmt.Errorf("key %q: %w", key, err)
+	if onError == nil {
+		log.Println("[WARN] Cannot obtain result", err)
+		return nil
+	}
+	return onError(err)
+}
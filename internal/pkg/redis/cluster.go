@@ -0,0 +1,114 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	redis "github.com/go-redis/redis"
+)
+
+// ErrFailingMaster thrown if a master of the cluster is flagged fail or fail?, as its keys cannot be read.
+var ErrFailingMaster = errors.New("failing cluster master")
+
+// ClusterMasters returns the config of the master nodes of a redis cluster, discovered from seed using CLUSTER NODES.
+// The seed can be any node of the cluster. The failing masters are excluded: if any, the other masters are returned
+// with an error wrapping ErrFailingMaster, for the caller to decide whether their keys may be missed.
+func ClusterMasters(seed Config) ([]Config, error) {
+	seed.Cluster = false
+	client := NewClient(seed)
+	defer client.Close()
+	nodes, err := client.redis.ClusterNodes().Result()
+	if err != nil {
+		return nil, err
+	}
+
+	seedHost, _, _ := net.SplitHostPort(seed.Address)
+	var masters []Config
+	var failing []string
+	// <id> <ip:port@cport[,hostname]> <flags> <master> <ping-sent> <pong-recv> <config-epoch> <link-state> <slot>...
+	for _, line := range strings.Split(nodes, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		flags := "," + fields[2] + ","
+		if !strings.Contains(flags, ",master,") || strings.Contains(flags, ",noaddr,") || strings.Contains(flags, ",handshake,") {
+			continue
+		}
+		address := strings.SplitN(strings.SplitN(fields[1], ",", 2)[0], "@", 2)[0]
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cluster node address %q: %w", fields[1], err)
+		}
+		// a node which has not met the others does not know its own ip
+		if host == "" {
+			host = seedHost
+		}
+		master := seed.node(net.JoinHostPort(host, port))
+		// fail, or fail? until the other nodes agree
+		if strings.Contains(flags, ",fail") {
+			failing = append(failing, master.Address)
+			continue
+		}
+		masters = append(masters, master)
+	}
+	if len(failing) > 0 {
+		return masters, fmt.Errorf("%w %v", ErrFailingMaster, strings.Join(failing, ", "))
+	}
+	return masters, nil
+}
+
+// SentinelMaster returns the config of the master named masterName, discovered from the sentinel
+// at sentinel.Address using SENTINEL get-master-addr-by-name. The rest of sentinel is used to connect to the master,
+// only its TLS and timeouts are used to connect to the sentinel.
+func SentinelMaster(sentinel Config, masterName string) (Config, error) {
+	opts := sentinel.options()
+	sentinelClient := redis.NewSentinelClient(&redis.Options{
+		Addr:         opts.Addr,
+		MaxRetries:   opts.MaxRetries,
+		DialTimeout:  opts.DialTimeout,
+		ReadTimeout:  opts.ReadTimeout,
+		WriteTimeout: opts.WriteTimeout,
+		TLSConfig:    opts.TLSConfig,
+	})
+	defer sentinelClient.Close()
+	address, err := sentinelClient.GetMasterAddrByName(masterName).Result()
+	if err != nil {
+		return Config{}, fmt.Errorf("cannot get master %q from sentinel %v: %w", masterName, sentinel.Address, err)
+	}
+	if len(address) != 2 {
+		return Config{}, fmt.Errorf("unexpected master %q address from sentinel %v: %v", masterName, sentinel.Address, address)
+	}
+	return sentinel.node(net.JoinHostPort(address[0], address[1])), nil
+}
+
+// ForEachNode calls fn for each node using at most concurrency goroutines, and waits until all of them return.
+// It returns the first error, the other nodes are not interrupted.
+// Use a SyncWriter to merge the output of the nodes, e.g. with a CSVSink for each node.
+func ForEachNode(nodes []Config, concurrency int, fn func(node Config) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	var once sync.Once
+	var firstErr error
+	semaphore := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+	for _, node := range nodes {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(node Config) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			if err := fn(node); err != nil {
+				once.Do(func() {
+					firstErr = fmt.Errorf("%v: %w", node.Address, err)
+				})
+			}
+		}(node)
+	}
+	wg.Wait()
+	return firstErr
+}
@@ -0,0 +1,184 @@
+package redis_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+
+	"github.com/keenangebze/go/internal/pkg/redis"
+)
+
+// standIn starts a fake redis answering the AUTH, CLUSTER NODES and SENTINEL get-master-addr-by-name commands.
+func standIn(nodes string, master *miniredis.Miniredis) *server.Server {
+	return standInTLS(nodes, master, nil)
+}
+
+// standInTLS is like standIn, accepting TLS connections if cfg is not nil.
+func standInTLS(nodes string, master *miniredis.Miniredis, cfg *tls.Config) *server.Server {
+	srv, err := server.NewServerTLS("127.0.0.1:0", cfg)
+	if cfg == nil {
+		srv, err = server.NewServer("127.0.0.1:0")
+	}
+	if err != nil {
+		panic(err)
+	}
+	srv.Register("AUTH", func(c *server.Peer, cmd string, args []string) {
+		c.WriteOK()
+	})
+	srv.Register("CLUSTER", func(c *server.Peer, cmd string, args []string) {
+		c.WriteBulk(nodes)
+	})
+	srv.Register("SENTINEL", func(c *server.Peer, cmd string, args []string) {
+		if len(args) != 2 || args[1] != "mymaster" {
+			c.WriteNull()
+			return
+		}
+		c.WriteStrings([]string{master.Host(), master.Port()})
+	})
+	return srv
+}
+
+// clusterNodes returns the CLUSTER NODES reply of a cluster made of the given masters, a replica and a failed master.
+func clusterNodes(masters ...*miniredis.Miniredis) string {
+	nodes := ""
+	for i, m := range masters {
+		port, _ := strconv.Atoi(m.Port())
+		nodes += fmt.Sprintf("id%v %v@%v master - 0 0 %v connected\n", i, m.Addr(), port+10000, i)
+	}
+	nodes += "id8 127.0.0.1:1@10001 slave id0 0 0 1 connected\n"
+	nodes += "id9 127.0.0.1:2@10002 master,fail - 0 0 9 connected\n"
+	return nodes
+}
+
+func TestClusterMasters(t *testing.T) {
+	a, b := miniredis.RunT(t), miniredis.RunT(t)
+	seed := standIn(clusterNodes(a, b), a)
+	defer seed.Close()
+
+	masters, err := redis.ClusterMasters(redis.Config{Address: seed.Addr().String(), Password: "secret"})
+	if !errors.Is(err, redis.ErrFailingMaster) || !strings.HasSuffix(err.Error(), "127.0.0.1:2") {
+		t.Fatalf("Expected the failing master 127.0.0.1:2 returned %v.\n", err)
+	}
+	expected := []redis.Config{{Address: a.Addr(), Password: "secret"}, {Address: b.Addr(), Password: "secret"}}
+	if fmt.Sprint(masters) != fmt.Sprint(expected) {
+		t.Fatalf("Expected %v and %v returned %v.\n", a.Addr(), b.Addr(), masters)
+	}
+}
+
+// selfSigned returns the TLS config of a server certified for localhost, and of a client trusting it.
+func selfSigned(t *testing.T) (*tls.Config, *tls.Config) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	server := &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	return server, &tls.Config{RootCAs: pool}
+}
+
+// TestNodesTLS asserts the nodes are verified with the host of the seed or the sentinel, not their IP.
+func TestNodesTLS(t *testing.T) {
+	a, b := miniredis.RunT(t), miniredis.RunT(t)
+	serverTLS, clientTLS := selfSigned(t)
+	seed := standInTLS(clusterNodes(a, b), a, serverTLS)
+	defer seed.Close()
+	_, port, _ := net.SplitHostPort(seed.Addr().String())
+
+	masters, err := redis.ClusterMasters(redis.Config{Address: "localhost:" + port, TLS: clientTLS})
+	if !errors.Is(err, redis.ErrFailingMaster) || len(masters) != 2 {
+		t.Fatalf("Expected 2 masters returned %v %v.\n", masters, err)
+	}
+	master, err := redis.SentinelMaster(redis.Config{Address: "localhost:" + port, TLS: clientTLS}, "mymaster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, node := range append(masters, master) {
+		if node.TLS.ServerName != "localhost" || node.TLS == clientTLS {
+			t.Errorf("Expected a copy of the TLS config verifying localhost for %v returned %q.\n", node.Address, node.TLS.ServerName)
+		}
+	}
+	if clientTLS.ServerName != "" {
+		t.Errorf("Expected the TLS config of the seed unchanged returned %q.\n", clientTLS.ServerName)
+	}
+}
+
+func TestSentinelMaster(t *testing.T) {
+	m := miniredis.RunT(t)
+	sentinel := standIn("", m)
+	defer sentinel.Close()
+
+	master, err := redis.SentinelMaster(redis.Config{Address: sentinel.Addr().String()}, "mymaster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if master.Address != net.JoinHostPort(m.Host(), m.Port()) {
+		t.Fatalf("Expected %v returned %v.\n", m.Addr(), master.Address)
+	}
+	if _, err := redis.SentinelMaster(redis.Config{Address: sentinel.Addr().String()}, "unknown"); err == nil {
+		t.Fatalf("Expected error for unknown master.\n")
+	}
+}
+
+// Dump the strings of every master of a cluster concurrently.
+func ExampleForEachNode() {
+	a, _ := miniredis.Run()
+	defer a.Close()
+	b, _ := miniredis.Run()
+	defer b.Close()
+	a.Set("user:1", "alice")
+	a.Set("user:2", "bob")
+	b.Set("user:3", "carol")
+
+	seed := standIn(clusterNodes(a, b), a)
+	defer seed.Close()
+	masters, _ := redis.ClusterMasters(redis.Config{Address: seed.Addr().String()})
+
+	out := redis.NewSyncWriter(os.Stdout)
+	redis.ForEachNode(masters, 2, func(node redis.Config) error {
+		client := redis.NewClient(node)
+		defer client.Close()
+		sink := redis.NewCSVSink(out, false)
+		if err := client.DumpStrings(context.Background(), redis.ScanOptions{Match: "user:*", Count: 10}, sink); err != nil {
+			return err
+		}
+		return sink.Flush()
+	})
+
+	// Unordered output:
+	// user:1,alice
+	// user:2,bob
+	// user:3,carol
+}
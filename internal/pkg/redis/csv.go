@@ -0,0 +1,163 @@
+package redis
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/keenangebze/go/pipeline"
+)
+
+// CSVSink writes the entries as CSV rows, one row per element:
+//
+//	string  key,value
+//	list    key,element
+//	zset    key,member,score
+//	hash    key,field,value
+//	set     key,member
+//	stream  key,id,["field","value"]
+//
+// With type, the type of the key is written as the first column, e.g. "zset,key,member,score".
+// A truncated value, see Entry.Truncated, ends with a row of the key alone.
+type CSVSink struct {
+	keyBuffer
+	typed bool
+	csv   *csv.Writer
+}
+
+// NewCSVSink returns a CSVSink writing to out, typed writes the type of the key as the first column.
+func NewCSVSink(out io.Writer, typed bool) *CSVSink {
+	s := CSVSink{keyBuffer: keyBuffer{out: out}, typed: typed}
+	s.csv = csv.NewWriter(&s.buf)
+	return &s
+}
+
+// Write implements pipeline.Sink.
+func (s *CSVSink) Write(ctx context.Context, entry Entry) error {
+	s.csv.Flush()
+	if err := s.start(entry.Key); err != nil {
+		return err
+	}
+
+	prefix := []string{entry.Key}
+	if s.typed {
+		prefix = []string{entry.Type, entry.Key}
+	}
+	width := elementWidth(entry.Type)
+	for i := 0; i+width <= len(entry.Values); i += width {
+		row := append(prefix[:len(prefix):len(prefix)], entry.Values[i:i+width]...)
+		if err := s.csv.Write(row); err != nil {
+			return err
+		}
+	}
+	if entry.Truncated {
+		return s.csv.Write(prefix)
+	}
+	return nil
+}
+
+// Flush writes the buffered rows.
+func (s *CSVSink) Flush() error {
+	s.csv.Flush()
+	if err := s.csv.Error(); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+// elementWidth returns the number of values making an element of the type, e.g. member and score for zset.
+func elementWidth(keyType string) int {
+	switch keyType {
+	case "zset", "hash", "stream":
+		return 2
+	}
+	return 1
+}
+
+// NewCSVSource returns a Source reading the CSV written by a CSVSink, each row is read as an entry.
+// keyType is the type of every row, or empty if the rows are typed.
+//
+// The older "key,members" sorted set rows without score are also accepted,
+// the rank of each member is then used as its score to keep the ordering.
+// The older "key,elements" list rows, joined by comma in one cell, cannot be told from an element
+// holding a comma: they are read as a single element, with a warning, see NewLegacyCSVSource.
+// Malformed rows are logged and skipped.
+func NewCSVSource(in io.Reader, keyType string) pipeline.Source[Entry] {
+	return newCSVSource(in, keyType, false)
+}
+
+// NewLegacyCSVSource returns a Source reading the CSV as NewCSVSource does,
+// except that the list rows are the older "key,elements" rows: the elements joined by comma are split.
+func NewLegacyCSVSource(in io.Reader, keyType string) pipeline.Source[Entry] {
+	return newCSVSource(in, keyType, true)
+}
+
+func newCSVSource(in io.Reader, keyType string, legacy bool) pipeline.Source[Entry] {
+	csvReader := csv.NewReader(in)
+	csvReader.FieldsPerRecord = -1
+	warned := false
+	return entrySource(func() (Entry, error) {
+		for {
+			row, err := csvReader.Read()
+			if err != nil {
+				if _, ok := err.(*csv.ParseError); ok {
+					log.Println("[WARN] Cannot read row", err)
+					continue
+				}
+				return Entry{}, err
+			}
+			entry, ok := csvEntry(row, keyType, legacy)
+			if !ok {
+				log.Println("[WARN] Unexpected row, skipping", row)
+				continue
+			}
+			if !legacy && !warned && entry.Type == "list" && len(entry.Values) == 1 && strings.Contains(entry.Values[0], ",") {
+				log.Println("[WARN] List element with comma read as a single element, use --legacy if the elements are joined by comma", entry.Key)
+				warned = true
+			}
+			return entry, nil
+		}
+	})
+}
+
+// csvEntry converts a CSV row in to an Entry, it returns false if the row is malformed.
+// legacy splits the list elements joined by comma.
+func csvEntry(row []string, keyType string, legacy bool) (Entry, bool) {
+	if keyType == "" {
+		if len(row) < 1 {
+			return Entry{}, false
+		}
+		keyType, row = row[0], row[1:]
+	}
+	if len(row) < 1 {
+		return Entry{}, false
+	}
+
+	entry := Entry{Type: keyType, Key: row[0], Values: row[1:]}
+	if len(row) == 1 && keyType != "string" {
+		// truncation marker
+		entry.Values, entry.Truncated = nil, true
+		return entry, true
+	}
+	if keyType == "zset" && len(row) == 2 {
+		// legacy members joined by comma without score
+		entry.Values = nil
+		if row[1] != "" {
+			for i, member := range strings.Split(row[1], ",") {
+				entry.Values = append(entry.Values, member, strconv.Itoa(i))
+			}
+		}
+		return entry, true
+	}
+	if keyType == "list" && legacy && len(row) == 2 {
+		entry.Values = nil
+		if row[1] != "" {
+			entry.Values = strings.Split(row[1], ",")
+		}
+		return entry, true
+	}
+	return entry, len(entry.Values) == elementWidth(keyType)
+}
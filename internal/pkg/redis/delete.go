@@ -0,0 +1,147 @@
+package redis
+
+import (
+	"context"
+	"log"
+
+	redis "github.com/go-redis/redis"
+
+	"github.com/keenangebze/go/pipeline"
+)
+
+// DeleteOptions configures Delete.
+type DeleteOptions struct {
+	// DryRun counts the keys selected without deleting them.
+	DryRun bool
+	// Backup receives the value and the TTL of each key before it is deleted, as DumpAll does, so it can be
+	// populated back. It is flushed once the keys of a page are written, before they are unlinked, and only
+	// the keys fully written are deleted, e.g. not the keys of an unsupported type nor a key failing midway.
+	// Nil means no backup.
+	Backup Encoder
+}
+
+// Delete deletes the keys selected by opts using UNLINK, one pipeline per page of keys,
+// so the memory is reclaimed in the background by redis. The pages are throttled by opts.Limits.
+// It returns the number of keys deleted, or the number of keys found with DryRun.
+//
+// Each key is unlinked by its own command, as a multi-key UNLINK fails across the slots of a cluster.
+func (c *Client) Delete(ctx context.Context, opts ScanOptions, del DeleteOptions) (int64, error) {
+	pipe := c.redis.Pipeline()
+	defer pipe.Close()
+
+	var deleted int64
+	it := c.ScanKeys(ctx, opts)
+	for it.Next() {
+		keys := it.Keys()
+		if del.DryRun {
+			if opts.Keys == nil && opts.KeyReader == nil {
+				deleted += int64(len(keys))
+				continue
+			}
+			// the exact keys may not exist
+			n, err := c.countExisting(it, pipe, keys)
+			if err != nil {
+				return deleted, err
+			}
+			deleted += n
+			continue
+		}
+
+		if del.Backup != nil {
+			var err error
+			if keys, err = c.backup(ctx, it, pipe, keys, del.Backup); err != nil {
+				return deleted, err
+			}
+		}
+		unlinks := make([]*redis.IntCmd, len(keys))
+		for i, key := range keys {
+			unlinks[i] = pipe.Unlink(key)
+		}
+		if err := it.wait(len(keys)); err != nil {
+			return deleted, err
+		}
+		if _, err := pipe.Exec(); err != nil && !isRedisError(err) {
+			return deleted, err
+		}
+		for i, key := range keys {
+			n, err := unlinks[i].Result()
+			if err != nil {
+				if err := keyError(opts.OnError, key, err); err != nil {
+					return deleted, err
+				}
+				continue
+			}
+			deleted += n
+		}
+	}
+	return deleted, it.Err()
+}
+
+// backup writes the keys to the sink then flushes it, it returns the keys written.
+// The keys not written are logged, except the keys deleted since scanned.
+func (c *Client) backup(ctx context.Context, it *KeyIterator, pipe redis.Pipeliner, keys []string, sink Encoder) ([]string, error) {
+	written := make(map[string]bool, len(keys))
+	write := pipeline.SinkFunc[Entry](func(ctx context.Context, entry Entry) error {
+		if err := sink.Write(ctx, entry); err != nil {
+			return err
+		}
+		written[entry.Key] = true
+		return nil
+	})
+	onError := func(key string, err error) error {
+		// the chunks of a big value already written are not a backup
+		delete(written, key)
+		return keyError(it.opts.OnError, key, err)
+	}
+	if err := c.dumpTyped(ctx, it, pipe, keys, write, true, onError); err != nil {
+		return nil, err
+	}
+	if err := sink.Flush(); err != nil {
+		return nil, err
+	}
+	backedUp := make([]string, 0, len(keys))
+	var missing []string
+	var exists []*redis.IntCmd
+	for _, key := range keys {
+		if written[key] {
+			backedUp = append(backedUp, key)
+			continue
+		}
+		missing = append(missing, key)
+		exists = append(exists, pipe.Exists(key))
+	}
+	if len(missing) == 0 {
+		return backedUp, nil
+	}
+	if err := it.wait(len(missing)); err != nil {
+		return nil, err
+	}
+	if _, err := pipe.Exec(); err != nil && !isRedisError(err) {
+		return nil, err
+	}
+	for i, key := range missing {
+		if exists[i].Val() > 0 {
+			log.Println("[WARN] Not backed up, skipping the deletion of key", key)
+		}
+	}
+	return backedUp, nil
+}
+
+// countExisting returns the number of the keys which exist.
+func (c *Client) countExisting(it *KeyIterator, pipe redis.Pipeliner, keys []string) (int64, error) {
+	exists := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		exists[i] = pipe.Exists(key)
+	}
+	if err := it.wait(len(keys)); err != nil {
+		return 0, err
+	}
+	if _, err := pipe.Exec(); err != nil {
+		return 0, err
+	}
+	var n int64
+	for _, cmd := range exists {
+		n += cmd.Val()
+	}
+	return n, nil
+}
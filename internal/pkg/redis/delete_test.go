@@ -0,0 +1,78 @@
+package redis_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/keenangebze/go/internal/pkg/redis"
+)
+
+func TestDelete(t *testing.T) {
+	s := miniredis.RunT(t)
+	s.Set("stale:1", "a")
+	s.RPush("stale:2", "b", "c")
+	s.Set("fresh", "d")
+	s.SetTTL("stale:2", time.Hour)
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+
+	n, err := client.Delete(ctx, redis.ScanOptions{Match: "stale:*", Count: 10}, redis.DeleteOptions{DryRun: true})
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 keys found returned %v %v.\n", n, err)
+	}
+	n, err = client.Delete(ctx, redis.ScanOptions{Keys: []string{"stale:1", "missing"}}, redis.DeleteOptions{DryRun: true})
+	if err != nil || n != 1 {
+		t.Fatalf("Expected 1 exact key found returned %v %v.\n", n, err)
+	}
+	if !s.Exists("stale:1") {
+		t.Fatalf("Expected no key deleted on dry run.\n")
+	}
+
+	backup := bytes.Buffer{}
+	sink := redis.NewBinarySink(&backup)
+	n, err = client.Delete(ctx, redis.ScanOptions{Match: "stale:*", Count: 1, BatchSize: 1}, redis.DeleteOptions{Backup: sink})
+	sink.Flush()
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 keys deleted returned %v %v.\n", n, err)
+	}
+	if s.Exists("stale:1") || s.Exists("stale:2") || !s.Exists("fresh") {
+		t.Fatalf("Expected only the stale keys deleted returned %v.\n", s.Keys())
+	}
+
+	// undo with populate
+	if _, err := client.Populate(ctx, redis.NewBinarySource(&backup), redis.PopulateOptions{BatchSize: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := s.List("stale:2"); len(v) != 2 || s.TTL("stale:2") != time.Hour || s.TTL("stale:1") != 0 {
+		t.Fatalf("Expected stale:2 restored with its TTL returned %v %v.\n", v, s.TTL("stale:2"))
+	}
+}
+
+// failWriter fails every write, e.g. a full disk.
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("no space left on device")
+}
+
+func TestDeleteBackupFailed(t *testing.T) {
+	s := miniredis.RunT(t)
+	s.Set("stale:1", "a")
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+
+	// the backup is buffered, the error of the flush must keep the keys
+	_, err := client.Delete(context.Background(), redis.ScanOptions{Match: "stale:*"}, redis.DeleteOptions{Backup: redis.NewBinarySink(failWriter{})})
+	if err == nil {
+		t.Fatalf("Expected the error of the backup returned nil.\n")
+	}
+	if !s.Exists("stale:1") {
+		t.Fatalf("Expected the key not backed up kept.\n")
+	}
+}
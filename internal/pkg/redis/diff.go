@@ -0,0 +1,596 @@
+package redis
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strconv"
+
+	redis "github.com/go-redis/redis"
+
+	"github.com/keenangebze/go/pipeline"
+)
+
+// Difference is a key which differs between a source and a target.
+type Difference struct {
+	Key string `json:"key"`
+	// Status is missing for a key only in the source, extra for a key only in the target, or changed.
+	Status string `json:"status"`
+	// Type and TargetType are the types of the key in the source and the target.
+	Type       string `json:"type,omitempty"`
+	TargetType string `json:"target_type,omitempty"`
+	// Elements is the number of the list indexes, members or fields which differ, for a changed key of the same type.
+	Elements int `json:"elements,omitempty"`
+	// Examples are some of the list indexes, members or fields which differ.
+	Examples []string `json:"examples,omitempty"`
+}
+
+// DiffStats counts the keys compared by a diff.
+type DiffStats struct {
+	// Keys is the number of keys of the source, of which Missing are not in the target and Changed differ.
+	Keys    int64
+	Missing int64
+	Changed int64
+	// Extra are the keys only in the target, see DiffExtra.
+	Extra int64
+}
+
+// KeySet is a set of keys, e.g. of a dump compared by DiffEntries.
+type KeySet map[string]struct{}
+
+// Exists reports which of the keys are in the set.
+func (s KeySet) Exists(keys []string) ([]bool, error) {
+	exists := make([]bool, len(keys))
+	for i, key := range keys {
+		_, exists[i] = s[key]
+	}
+	return exists, nil
+}
+
+// Diff compares the keys selected by opts with the target, by type and value, and writes the missing
+// and changed keys to the sink. Both sides are read as DumpAll does, then compared regardless of the order
+// of the members of a set, the fields of a hash, and the members of a sorted set with the same score.
+// A value of more than opts.Count elements on either side is compared chunk by chunk of opts.Count elements,
+// so a big key is not held whole. Use DiffExtra to find the keys only in the target.
+func (c *Client) Diff(ctx context.Context, to *Client, opts ScanOptions, sink pipeline.Sink[Difference]) (DiffStats, error) {
+	stats := DiffStats{}
+	count := opts.Count
+	if count <= 0 {
+		count = defaultPageSize
+	}
+	src := c.redis.Pipeline()
+	defer src.Close()
+	dst := to.redis.Pipeline()
+	defer dst.Close()
+
+	it := c.ScanKeys(ctx, opts)
+	for it.Next() {
+		keys := it.Keys()
+		types, errs, err := readTypes(it.wait, src, keys)
+		if err != nil {
+			return stats, err
+		}
+		targetTypes, targetErrs, err := readTypes(it.wait, dst, keys)
+		if err != nil {
+			return stats, err
+		}
+		lengths, err := readLengths(it.wait, src, keys, types)
+		if err != nil {
+			return stats, err
+		}
+		targetLengths, err := readLengths(it.wait, dst, keys, targetTypes)
+		if err != nil {
+			return stats, err
+		}
+		// only the values of the keys of the same type are read, and the big ones chunk by chunk
+		skip := make([]bool, len(keys))
+		for i := range keys {
+			skip[i] = types[i] != targetTypes[i] || lengths[i] > count || targetLengths[i] > count
+		}
+		values, err := readValues(it.wait, src, keys, types, errs, skip)
+		if err != nil {
+			return stats, err
+		}
+		targetValues, err := readValues(it.wait, dst, keys, targetTypes, targetErrs, skip)
+		if err != nil {
+			return stats, err
+		}
+		for i, key := range keys {
+			if types[i] == "none" {
+				// deleted since scanned
+				continue
+			}
+			err := firstError(errs[i], targetErrs[i])
+			switch {
+			case err != nil:
+			case skip[i] && types[i] == targetTypes[i]:
+				// an error reply reading a chunk, e.g. the key was retyped since, is an error of the key
+				if err = c.diffChunked(ctx, it.wait, dst, &stats, sink, key, types[i], count); err != nil && !isRedisError(err) {
+					return stats, err
+				}
+			default:
+				if err := diff(ctx, &stats, sink, key, types[i], targetTypes[i], values[i], targetValues[i], false); err != nil {
+					return stats, err
+				}
+			}
+			if err != nil {
+				if err := keyError(opts.OnError, key, err); err != nil {
+					return stats, err
+				}
+			}
+		}
+	}
+	return stats, it.Err()
+}
+
+// diffChunked compares the big value of a key with the target chunk by chunk, see elementDiff.
+func (c *Client) diffChunked(ctx context.Context, wait func(ops int) error, dst redis.Pipeliner, stats *DiffStats, sink pipeline.Sink[Difference], key, keyType string, count int64) error {
+	d := &elementDiff{key: key, keyType: keyType}
+	err := c.iterateValue(keyType, key, count, func(values []string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := wait(1); err != nil {
+			return err
+		}
+		return d.compare(wait, dst, values)
+	})
+	if err != nil {
+		return err
+	}
+	elements, examples, err := d.finish(wait, dst)
+	if err != nil {
+		return err
+	}
+	return diffCounted(ctx, stats, sink, key, keyType, elements, examples)
+}
+
+// DiffEntries compares the entries of a dump, e.g. read by NewDecoder, with the keys of the client as Diff does.
+// The entries of a key are merged, and the keys are compared in pipelines of opts.BatchSize throttled by opts.Limits.
+// The entries of a key of more than opts.Count elements, or whose value in the client has more, are compared
+// one by one instead, the other options are ignored. Only the type of a truncated entry is compared.
+// It returns the set of the keys of the dump, to find the keys only in the client with DiffExtra.
+func (c *Client) DiffEntries(ctx context.Context, source pipeline.Source[Entry], opts ScanOptions, sink pipeline.Sink[Difference]) (DiffStats, KeySet, error) {
+	stats := DiffStats{}
+	seen := KeySet{}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	count := opts.Count
+	if count <= 0 {
+		count = defaultPageSize
+	}
+	throttle := newThrottle(c, opts.Limits)
+	wait := func(ops int) error {
+		return throttle.wait(ctx, ops, 0)
+	}
+	pipe := c.redis.Pipeline()
+	defer pipe.Close()
+
+	flush := func(batch []Entry) error {
+		keys := make([]string, len(batch))
+		for i, entry := range batch {
+			keys[i] = entry.Key
+		}
+		targetTypes, targetErrs, err := readTypes(wait, pipe, keys)
+		if err != nil {
+			return err
+		}
+		targetLengths, err := readLengths(wait, pipe, keys, targetTypes)
+		if err != nil {
+			return err
+		}
+		skip := make([]bool, len(batch))
+		for i, entry := range batch {
+			skip[i] = entry.Type != targetTypes[i] || entry.Truncated || targetLengths[i] > count
+		}
+		targetValues, err := readValues(wait, pipe, keys, targetTypes, targetErrs, skip)
+		if err != nil {
+			return err
+		}
+		for i, entry := range batch {
+			err := targetErrs[i]
+			switch {
+			case err != nil:
+			case skip[i] && entry.Type == targetTypes[i] && !entry.Truncated:
+				// the value in the client is big, the entry is compared with the elements it names
+				d := &elementDiff{key: entry.Key, keyType: entry.Type}
+				var elements int
+				var examples []string
+				if err = d.compare(wait, pipe, entry.Values); err == nil {
+					elements, examples, err = d.finish(wait, pipe)
+				}
+				if err == nil {
+					err = diffCounted(ctx, &stats, sink, entry.Key, entry.Type, elements, examples)
+				}
+				if err != nil && !isRedisError(err) {
+					return err
+				}
+			default:
+				values := normalize(entry.Type, entry.Values)
+				if err := diff(ctx, &stats, sink, entry.Key, entry.Type, targetTypes[i], values, targetValues[i], entry.Truncated); err != nil {
+					return err
+				}
+			}
+			if err != nil {
+				if err := keyError(opts.OnError, entry.Key, err); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	// big is the key being compared entry by entry, as it has too many elements to merge
+	var big *entryDiff
+	compareBig := func(entry Entry) error {
+		big.truncated = big.truncated || entry.Truncated
+		if big.err != nil || big.truncated || big.targetType != big.keyType {
+			return nil
+		}
+		if err := big.compare(wait, pipe, entry.Values); err != nil {
+			if !isRedisError(err) {
+				return err
+			}
+			big.err = err
+		}
+		return nil
+	}
+	startBig := func(entry Entry) error {
+		targetTypes, targetErrs, err := readTypes(wait, pipe, []string{entry.Key})
+		if err != nil {
+			return err
+		}
+		big = &entryDiff{elementDiff: elementDiff{key: entry.Key, keyType: entry.Type}, targetType: targetTypes[0], err: targetErrs[0]}
+		return compareBig(entry)
+	}
+	finishBig := func() error {
+		d := big
+		big = nil
+		switch {
+		case d.err == nil && (d.truncated || d.targetType != d.keyType):
+			return diff(ctx, &stats, sink, d.key, d.keyType, d.targetType, nil, nil, d.truncated)
+		case d.err == nil:
+			elements, examples, err := d.finish(wait, pipe)
+			if err == nil {
+				return diffCounted(ctx, &stats, sink, d.key, d.keyType, elements, examples)
+			}
+			if !isRedisError(err) {
+				return err
+			}
+			d.err = err
+		}
+		return keyError(opts.OnError, d.key, d.err)
+	}
+
+	batch := make([]Entry, 0, opts.BatchSize)
+	for {
+		entry, err := source.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stats, seen, err
+		}
+		if err := ctx.Err(); err != nil {
+			return stats, seen, err
+		}
+		seen[entry.Key] = struct{}{}
+		if big != nil && big.key == entry.Key {
+			if err := compareBig(entry); err != nil {
+				return stats, seen, err
+			}
+			continue
+		}
+		if big != nil {
+			if err := finishBig(); err != nil {
+				return stats, seen, err
+			}
+		}
+		if n := len(batch); n > 0 && batch[n-1].Key == entry.Key {
+			last := batch[n-1]
+			if int64((len(last.Values)+len(entry.Values))/elementWidth(entry.Type)) > count {
+				batch = batch[:n-1]
+				if err := startBig(last); err != nil {
+					return stats, seen, err
+				}
+				if err := compareBig(entry); err != nil {
+					return stats, seen, err
+				}
+				continue
+			}
+			batch[n-1].Values = append(last.Values, entry.Values...)
+			batch[n-1].Truncated = last.Truncated || entry.Truncated
+			continue
+		}
+		// the batch is only compared once the next key is read, so the chunks of its last key are complete
+		if len(batch) == opts.BatchSize {
+			if err := flush(batch); err != nil {
+				return stats, seen, err
+			}
+			batch = batch[:0]
+		}
+		batch = append(batch, entry)
+	}
+	if big != nil {
+		if err := finishBig(); err != nil {
+			return stats, seen, err
+		}
+	}
+	if len(batch) > 0 {
+		if err := flush(batch); err != nil {
+			return stats, seen, err
+		}
+	}
+	return stats, seen, nil
+}
+
+// entryDiff compares the entries of a key of DiffEntries one by one, see elementDiff.
+type entryDiff struct {
+	elementDiff
+	targetType string
+	truncated  bool
+	// err is the error reading the key in the target, reported once all its entries are read.
+	err error
+}
+
+// DiffExtra writes the keys selected by opts which are not in the source of a diff to the sink as extra.
+// exists reports which of the keys are in the source, e.g. Client.Exists or the KeySet of DiffEntries.
+// It returns the number of extra keys.
+func (c *Client) DiffExtra(ctx context.Context, opts ScanOptions, exists func(keys []string) ([]bool, error), sink pipeline.Sink[Difference]) (int64, error) {
+	var extra int64
+	it := c.ScanKeys(ctx, opts)
+	for it.Next() {
+		keys := it.Keys()
+		if err := it.wait(len(keys)); err != nil {
+			return extra, err
+		}
+		found, err := exists(keys)
+		if err != nil {
+			return extra, err
+		}
+		for i, key := range keys {
+			if found[i] {
+				continue
+			}
+			extra++
+			if err := sink.Write(ctx, Difference{Key: key, Status: "extra"}); err != nil {
+				return extra, err
+			}
+		}
+	}
+	return extra, it.Err()
+}
+
+// Exists reports which of the keys exist, using a pipeline of EXISTS.
+func (c *Client) Exists(keys []string) ([]bool, error) {
+	pipe := c.redis.Pipeline()
+	defer pipe.Close()
+	cmds := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.Exists(key)
+	}
+	if _, err := pipe.Exec(); err != nil {
+		return nil, err
+	}
+	exists := make([]bool, len(keys))
+	for i, cmd := range cmds {
+		exists[i] = cmd.Val() > 0
+	}
+	return exists, nil
+}
+
+// diff counts the key and writes it to the sink if it is missing from the target or changed.
+// truncated only compares the types, as the source value is incomplete.
+func diff(ctx context.Context, stats *DiffStats, sink pipeline.Sink[Difference], key, keyType, targetType string, values, targetValues []string, truncated bool) error {
+	stats.Keys++
+	difference := Difference{Key: key, Type: keyType, TargetType: targetType}
+	switch {
+	case targetType == "none":
+		stats.Missing++
+		difference.Status = "missing"
+		difference.TargetType = ""
+	case keyType != targetType:
+		stats.Changed++
+		difference.Status = "changed"
+	case truncated || equalValues(values, targetValues):
+		return nil
+	default:
+		stats.Changed++
+		difference.Status = "changed"
+		difference.Elements, difference.Examples = diffElements(keyType, values, targetValues)
+	}
+	return sink.Write(ctx, difference)
+}
+
+// diffCounted counts the key compared chunk by chunk, and writes it to the sink if some of its elements differ.
+func diffCounted(ctx context.Context, stats *DiffStats, sink pipeline.Sink[Difference], key, keyType string, elements int, examples []string) error {
+	stats.Keys++
+	if elements == 0 {
+		return nil
+	}
+	stats.Changed++
+	return sink.Write(ctx, Difference{Key: key, Status: "changed", Type: keyType, TargetType: keyType, Elements: elements, Examples: examples})
+}
+
+// diffElements returns the number and some examples of the list indexes, members or fields which differ
+// between the normalized values of a key, see normalize.
+func diffElements(keyType string, a, b []string) (int, []string) {
+	var elements []string
+	switch keyType {
+	case "list":
+		for i := 0; i < len(a) || i < len(b); i++ {
+			if i >= len(a) || i >= len(b) || a[i] != b[i] {
+				elements = append(elements, strconv.Itoa(i))
+			}
+		}
+	case "set":
+		elements = symmetricDifference(a, b, 1)
+	case "hash", "zset", "stream":
+		elements = symmetricDifference(a, b, 2)
+	}
+	n := len(elements)
+	if len(elements) > maxExamples {
+		elements = elements[:maxExamples]
+	}
+	return n, elements
+}
+
+// symmetricDifference returns the members, or the fields with their values when stride is 2,
+// which are not in both values or whose values differ.
+func symmetricDifference(a, b []string, stride int) []string {
+	elements := func(values []string) map[string]string {
+		m := make(map[string]string, len(values)/stride)
+		for i := 0; i+stride <= len(values); i += stride {
+			m[values[i]] = values[i+stride-1]
+		}
+		return m
+	}
+	ma, mb := elements(a), elements(b)
+	var differ []string
+	for member, value := range ma {
+		if other, ok := mb[member]; !ok || other != value {
+			differ = append(differ, member)
+		}
+	}
+	for member := range mb {
+		if _, ok := ma[member]; !ok {
+			differ = append(differ, member)
+		}
+	}
+	sort.Strings(differ)
+	return differ
+}
+
+// typedValues pipelines the TYPE of the keys, then their values as DumpAll does, normalized for comparison.
+// The type of a key which does not exist is "none". wait throttles the pipelines.
+// The error of reading each key, e.g. ErrUnsupportedType, is returned in errs, err is the error of the pipelines.
+func typedValues(wait func(ops int) error, pipe redis.Pipeliner, keys []string) (types []string, values [][]string, errs []error, err error) {
+	types, errs, err = readTypes(wait, pipe, keys)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	values, err = readValues(wait, pipe, keys, types, errs, nil)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return types, values, errs, nil
+}
+
+// readTypes pipelines the TYPE of the keys, returning the error of reading each key in errs,
+// e.g. ErrUnsupportedType.
+func readTypes(wait func(ops int) error, pipe redis.Pipeliner, keys []string) (types []string, errs []error, err error) {
+	cmds := make([]*redis.StatusCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.Type(key)
+	}
+	if err := wait(len(keys)); err != nil {
+		return nil, nil, err
+	}
+	if _, err := pipe.Exec(); err != nil && !isRedisError(err) {
+		return nil, nil, err
+	}
+	types = make([]string, len(keys))
+	errs = make([]error, len(keys))
+	for i, cmd := range cmds {
+		if types[i], errs[i] = cmd.Result(); errs[i] == nil {
+			errs[i] = unsupportedType(types[i])
+		}
+	}
+	return types, errs, nil
+}
+
+// readValues pipelines the values of the keys of the types read by readTypes, except the skipped ones,
+// and normalizes them for comparison. The errors of reading each key are set in errs,
+// and the type of a key deleted since its type was read is set to "none".
+func readValues(wait func(ops int) error, pipe redis.Pipeliner, keys, types []string, errs []error, skip []bool) ([][]string, error) {
+	fetches := make([]fetch, len(keys))
+	for i, key := range keys {
+		if errs[i] != nil || skip != nil && skip[i] {
+			continue
+		}
+		if fetches[i] = typedValue(pipe, types[i], key); fetches[i] == nil {
+			errs[i] = unsupportedType(types[i])
+		}
+	}
+	if err := wait(len(keys)); err != nil {
+		return nil, err
+	}
+	if _, err := pipe.Exec(); err != nil && !isRedisError(err) {
+		return nil, err
+	}
+	values := make([][]string, len(keys))
+	for i := range keys {
+		if fetches[i] == nil {
+			continue
+		}
+		values[i], errs[i] = fetches[i]()
+		if errs[i] == redis.Nil {
+			// deleted since the type was read
+			types[i], errs[i] = "none", nil
+		}
+		values[i] = normalize(types[i], values[i])
+	}
+	return values, nil
+}
+
+// firstError returns the first error which is not nil.
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalize orders the value of a key for comparison: the members of a set, the fields of a hash,
+// and the members of a sorted set with the same score are sorted, and the scores are formatted alike.
+func normalize(keyType string, values []string) []string {
+	switch keyType {
+	case "set":
+		values = append([]string(nil), values...)
+		sort.Strings(values)
+	case "hash", "zset":
+		pairs := make([][2]string, 0, len(values)/2)
+		for i := 0; i+1 < len(values); i += 2 {
+			pairs = append(pairs, [2]string{values[i], values[i+1]})
+		}
+		var scores []float64
+		if keyType == "zset" {
+			scores = make([]float64, len(pairs))
+			for i := range pairs {
+				scores[i], _ = strconv.ParseFloat(pairs[i][1], 64)
+				pairs[i][1] = strconv.FormatFloat(scores[i], 'f', -1, 64)
+			}
+		}
+		sort.Sort(pairSorter{pairs, scores})
+		values = make([]string, 0, len(pairs)*2)
+		for _, pair := range pairs {
+			values = append(values, pair[0], pair[1])
+		}
+	}
+	return values
+}
+
+// pairSorter sorts the pairs of a hash by field, or of a sorted set by score then member.
+type pairSorter struct {
+	pairs  [][2]string
+	scores []float64
+}
+
+func (s pairSorter) Len() int { return len(s.pairs) }
+
+func (s pairSorter) Less(i, j int) bool {
+	if s.scores != nil && s.scores[i] != s.scores[j] {
+		return s.scores[i] < s.scores[j]
+	}
+	return s.pairs[i][0] < s.pairs[j][0]
+}
+
+func (s pairSorter) Swap(i, j int) {
+	s.pairs[i], s.pairs[j] = s.pairs[j], s.pairs[i]
+	if s.scores != nil {
+		s.scores[i], s.scores[j] = s.scores[j], s.scores[i]
+	}
+}
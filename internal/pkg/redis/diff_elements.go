@@ -0,0 +1,230 @@
+package redis
+
+import (
+	"strconv"
+
+	redis "github.com/go-redis/redis"
+)
+
+// elementDiff compares a big value with the target chunk by chunk, so neither value is held whole:
+// each chunk of the source is compared with the elements of the target it names,
+// then the length of the target counts the elements only in the target.
+type elementDiff struct {
+	key, keyType string
+	// elements is the number of elements of the source compared, of which missing are not in the target.
+	elements, missing int64
+	differ            int
+	examples          []string
+}
+
+// add counts an element which differs, keeping it as an example.
+func (d *elementDiff) add(element string) {
+	d.differ++
+	if len(d.examples) < maxExamples {
+		d.examples = append(d.examples, element)
+	}
+}
+
+// compare compares the next chunk of the source value, as read by iterateValue or written by a dump,
+// with the target using the pipeline. wait throttles the pipeline.
+func (d *elementDiff) compare(wait func(ops int) error, pipe redis.Pipeliner, values []string) error {
+	switch d.keyType {
+	case "list":
+		cmd := pipe.LRange(d.key, d.elements, d.elements+int64(len(values))-1)
+		if err := exec(wait, pipe, 1); err != nil {
+			return err
+		}
+		target, err := cmd.Result()
+		if err != nil {
+			return err
+		}
+		for i, value := range values {
+			if i >= len(target) || target[i] != value {
+				d.add(strconv.FormatInt(d.elements+int64(i), 10))
+			}
+		}
+		d.elements += int64(len(values))
+	case "set":
+		cmds := make([]*redis.BoolCmd, len(values))
+		for i, member := range values {
+			cmds[i] = pipe.SIsMember(d.key, member)
+		}
+		if err := exec(wait, pipe, len(cmds)); err != nil {
+			return err
+		}
+		for i, cmd := range cmds {
+			found, err := cmd.Result()
+			if err != nil {
+				return err
+			}
+			if !found {
+				d.missing++
+				d.add(values[i])
+			}
+		}
+		d.elements += int64(len(values))
+	case "hash":
+		fields := make([]string, 0, len(values)/2)
+		for i := 0; i+1 < len(values); i += 2 {
+			fields = append(fields, values[i])
+		}
+		if len(fields) == 0 {
+			return nil
+		}
+		cmd := pipe.HMGet(d.key, fields...)
+		if err := exec(wait, pipe, 1); err != nil {
+			return err
+		}
+		target, err := cmd.Result()
+		if err != nil {
+			return err
+		}
+		for i, field := range fields {
+			value, ok := target[i].(string)
+			if !ok {
+				d.missing++
+			}
+			if !ok || value != values[2*i+1] {
+				d.add(field)
+			}
+		}
+		d.elements += int64(len(fields))
+	case "zset":
+		cmds := make([]*redis.FloatCmd, 0, len(values)/2)
+		for i := 0; i+1 < len(values); i += 2 {
+			cmds = append(cmds, pipe.ZScore(d.key, values[i]))
+		}
+		if err := exec(wait, pipe, len(cmds)); err != nil {
+			return err
+		}
+		for i, cmd := range cmds {
+			score, err := cmd.Result()
+			if err == redis.Nil {
+				d.missing++
+				d.add(values[2*i])
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if source, _ := strconv.ParseFloat(values[2*i+1], 64); source != score {
+				d.add(values[2*i])
+			}
+		}
+		d.elements += int64(len(cmds))
+	case "stream":
+		cmds := make([]*redis.Cmd, 0, len(values)/2)
+		for i := 0; i+1 < len(values); i += 2 {
+			cmds = append(cmds, pipe.Do("XRANGE", d.key, values[i], values[i]))
+		}
+		if err := exec(wait, pipe, len(cmds)); err != nil {
+			return err
+		}
+		for i, cmd := range cmds {
+			reply, err := cmd.Result()
+			if err != nil {
+				return err
+			}
+			target, err := streamValues(reply)
+			if err != nil {
+				return err
+			}
+			if len(target) < 2 {
+				d.missing++
+			}
+			if len(target) < 2 || target[1] != values[2*i+1] {
+				d.add(values[2*i])
+			}
+		}
+		d.elements += int64(len(cmds))
+	}
+	return nil
+}
+
+// finish counts the elements only in the target from the length of the target value,
+// and returns the number and some examples of the elements which differ.
+func (d *elementDiff) finish(wait func(ops int) error, pipe redis.Pipeliner) (int, []string, error) {
+	cmd := queueLength(pipe, d.keyType, d.key)
+	if err := exec(wait, pipe, 1); err != nil {
+		return 0, nil, err
+	}
+	length, err := cmd.Result()
+	if err != nil {
+		return 0, nil, err
+	}
+	// the indexes past the end of the source differ, the other elements only in the target have no name to show
+	extra := length - (d.elements - d.missing)
+	if d.keyType == "list" {
+		extra = length - d.elements
+		for i := d.elements; i < length && len(d.examples) < maxExamples; i++ {
+			d.examples = append(d.examples, strconv.FormatInt(i, 10))
+		}
+	}
+	if extra > 0 {
+		d.differ += int(extra)
+	}
+	return d.differ, d.examples, nil
+}
+
+// iterateValue iterates the value of a big key in chunks of count elements, as DumpLists and DumpAll read them,
+// calling fn with each chunk.
+func (c *Client) iterateValue(keyType, key string, count int64, fn func(values []string) error) error {
+	switch keyType {
+	case "list":
+		for start := int64(0); ; start += count {
+			values, err := c.redis.LRange(key, start, start+count-1).Result()
+			if err != nil {
+				return err
+			}
+			if len(values) > 0 {
+				if err := fn(values); err != nil {
+					return err
+				}
+			}
+			if int64(len(values)) < count {
+				return nil
+			}
+		}
+	case "set":
+		return iterateElements(c.redis.SScan, key, count, fn)
+	case "hash":
+		return iterateElements(c.redis.HScan, key, count, fn)
+	case "zset":
+		return iterateElements(c.redis.ZScan, key, count, fn)
+	case "stream":
+		return iterateStream(c.do, key, count, fn)
+	}
+	return unsupportedType(keyType)
+}
+
+// exec throttles then executes the commands queued in the pipeline, keeping the error replies in the commands.
+func exec(wait func(ops int) error, pipe redis.Pipeliner, ops int) error {
+	if err := wait(ops); err != nil {
+		return err
+	}
+	if _, err := pipe.Exec(); err != nil && !isRedisError(err) {
+		return err
+	}
+	return nil
+}
+
+// readLengths pipelines the number of elements of the keys of a list, set, sorted set, hash or stream,
+// the length of the other keys is 0.
+func readLengths(wait func(ops int) error, pipe redis.Pipeliner, keys, types []string) ([]int64, error) {
+	cmds := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		if types[i] != "string" {
+			cmds[i] = queueLength(pipe, types[i], key)
+		}
+	}
+	if err := exec(wait, pipe, len(keys)); err != nil {
+		return nil, err
+	}
+	lengths := make([]int64, len(keys))
+	for i, cmd := range cmds {
+		if cmd != nil {
+			lengths[i] = cmd.Val()
+		}
+	}
+	return lengths, nil
+}
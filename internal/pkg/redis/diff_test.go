@@ -0,0 +1,240 @@
+package redis_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/keenangebze/go/internal/pkg/redis"
+	"github.com/keenangebze/go/pipeline"
+)
+
+// differences collects the differences written to the sink, sorted by key.
+type differences []redis.Difference
+
+func (d *differences) Write(ctx context.Context, difference redis.Difference) error {
+	*d = append(*d, difference)
+	sort.Slice(*d, func(i, j int) bool { return (*d)[i].Key < (*d)[j].Key })
+	return nil
+}
+
+func TestDiff(t *testing.T) {
+	src := miniredis.RunT(t)
+	src.Set("same", "a")
+	src.Set("changed", "a")
+	src.Set("missing", "a")
+	src.RPush("list", "a", "b", "c")
+	src.HSet("hash", "name", "toko")
+	src.HSet("hash", "city", "jakarta")
+	src.ZAdd("zset", 1, "a")
+	src.ZAdd("zset", 1, "b")
+	src.SAdd("set", "a", "b")
+	src.Set("retyped", "a")
+
+	dst := miniredis.RunT(t)
+	dst.Set("same", "a")
+	dst.Set("changed", "b")
+	dst.RPush("list", "a", "x", "c", "d")
+	dst.HSet("hash", "city", "jakarta")
+	dst.HSet("hash", "name", "pedia")
+	dst.ZAdd("zset", 1, "b")
+	dst.ZAdd("zset", 1, "a")
+	dst.SAdd("set", "b", "a")
+	dst.RPush("retyped", "a")
+	dst.Set("extra", "a")
+
+	client := redis.NewClient(redis.Config{Address: src.Addr()})
+	defer client.Close()
+	to := redis.NewClient(redis.Config{Address: dst.Addr()})
+	defer to.Close()
+	ctx := context.Background()
+	opts := redis.ScanOptions{Match: "*", Count: 10, BatchSize: 3}
+
+	got := differences{}
+	stats, err := client.Diff(ctx, to, opts, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extra, err := to.DiffExtra(ctx, opts, client.Exists, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (redis.DiffStats{Keys: 8, Missing: 1, Changed: 4}) || extra != 1 {
+		t.Errorf("Expected 8 keys, 1 missing, 4 changed and 1 extra returned %+v %v.\n", stats, extra)
+	}
+	expected := differences{
+		{Key: "changed", Status: "changed", Type: "string", TargetType: "string"},
+		{Key: "extra", Status: "extra"},
+		{Key: "hash", Status: "changed", Type: "hash", TargetType: "hash", Elements: 1, Examples: []string{"name"}},
+		{Key: "list", Status: "changed", Type: "list", TargetType: "list", Elements: 2, Examples: []string{"1", "3"}},
+		{Key: "missing", Status: "missing", Type: "string"},
+		{Key: "retyped", Status: "changed", Type: "string", TargetType: "list"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected differences %+v returned %+v.\n", expected, got)
+	}
+}
+
+// TestDiffErrors asserts a key which cannot be read is reported to OnError, not compared as empty.
+func TestDiffErrors(t *testing.T) {
+	src, dst := miniredis.RunT(t), miniredis.RunT(t)
+	for _, s := range []*miniredis.Miniredis{src, dst} {
+		s.Set("same", "a")
+		// miniredis has its own type for HyperLogLog
+		s.PfAdd("visitors", "a")
+	}
+	dst.Set("retyped", "a")
+	src.PfAdd("retyped", "a")
+	client := redis.NewClient(redis.Config{Address: src.Addr()})
+	defer client.Close()
+	to := redis.NewClient(redis.Config{Address: dst.Addr()})
+	defer to.Close()
+
+	var failed []string
+	opts := redis.ScanOptions{Match: "*", Count: 10, OnError: func(err error) error {
+		if !errors.Is(err, redis.ErrUnsupportedType) {
+			return err
+		}
+		failed = append(failed, err.Error())
+		return nil
+	}}
+	got := differences{}
+	stats, err := client.Diff(context.Background(), to, opts, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(failed)
+	if len(got) != 0 || stats.Keys != 1 || len(failed) != 2 || !strings.HasPrefix(failed[0], `key "retyped"`) {
+		t.Errorf("Expected the unsupported keys reported to OnError returned %+v %+v %v.\n", got, stats, failed)
+	}
+}
+
+// TestDiffChunked asserts the values longer than Count are compared chunk by chunk, as Diff and DiffEntries do.
+func TestDiffChunked(t *testing.T) {
+	src, dst := miniredis.RunT(t), miniredis.RunT(t)
+	src.RPush("list", "a", "b", "c", "d", "e")
+	dst.RPush("list", "a", "x", "c", "d")
+	src.RPush("long", "a")
+	dst.RPush("long", "a", "b", "c")
+	src.SAdd("set", "a", "b", "c")
+	dst.SAdd("set", "a", "b", "d")
+	for _, s := range []*miniredis.Miniredis{src, dst} {
+		s.HSet("hash", "f1", "1", "f3", "3")
+		s.ZAdd("zset", 1, "a")
+		s.ZAdd("zset", 2, "b")
+		s.ZAdd("zset", 3, "c")
+		s.XAdd("stream", "1-0", []string{"f", "a"})
+		s.XAdd("stream", "2-0", []string{"f", "b"})
+	}
+	src.HSet("hash", "f2", "2")
+	dst.HSet("hash", "f2", "x")
+	src.XAdd("stream", "3-0", []string{"f", "c"})
+	client := redis.NewClient(redis.Config{Address: src.Addr()})
+	defer client.Close()
+	to := redis.NewClient(redis.Config{Address: dst.Addr()})
+	defer to.Close()
+	ctx := context.Background()
+	opts := redis.ScanOptions{Match: "*", Count: 2}
+
+	expected := differences{
+		{Key: "hash", Status: "changed", Type: "hash", TargetType: "hash", Elements: 1, Examples: []string{"f2"}},
+		{Key: "list", Status: "changed", Type: "list", TargetType: "list", Elements: 2, Examples: []string{"1", "4"}},
+		{Key: "long", Status: "changed", Type: "list", TargetType: "list", Elements: 2, Examples: []string{"1", "2"}},
+		{Key: "set", Status: "changed", Type: "set", TargetType: "set", Elements: 2, Examples: []string{"c"}},
+		{Key: "stream", Status: "changed", Type: "stream", TargetType: "stream", Elements: 1, Examples: []string{"3-0"}},
+	}
+	got := differences{}
+	stats, err := client.Diff(ctx, to, opts, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (redis.DiffStats{Keys: 6, Changed: 5}) || !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected differences %+v returned %+v %+v.\n", expected, stats, got)
+	}
+
+	// a dump with an entry per element, merged beyond Count
+	var entries []redis.Entry
+	split := pipeline.SinkFunc[redis.Entry](func(ctx context.Context, entry redis.Entry) error {
+		width := 1
+		if entry.Type == "hash" || entry.Type == "zset" || entry.Type == "stream" {
+			width = 2
+		}
+		for i := 0; i < len(entry.Values); i += width {
+			entries = append(entries, redis.Entry{Type: entry.Type, Key: entry.Key, Values: entry.Values[i : i+width]})
+		}
+		return nil
+	})
+	if err := client.DumpAll(ctx, opts, split); err != nil {
+		t.Fatal(err)
+	}
+	source := pipeline.SourceFunc[redis.Entry](func(ctx context.Context) (redis.Entry, error) {
+		if len(entries) == 0 {
+			return redis.Entry{}, io.EOF
+		}
+		entry := entries[0]
+		entries = entries[1:]
+		return entry, nil
+	})
+	got = differences{}
+	stats, _, err = to.DiffEntries(ctx, source, opts, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (redis.DiffStats{Keys: 6, Changed: 5}) || !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected the entries differences %+v returned %+v %+v.\n", expected, stats, got)
+	}
+}
+
+func TestDiffEntries(t *testing.T) {
+	s := miniredis.RunT(t)
+	s.ZAdd("zset", 1.5, "a")
+	s.ZAdd("zset", 2, "b")
+	s.Set("changed", "b")
+	s.Set("extra", "a")
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+
+	// a dump split in chunks, with a score formatted differently
+	dump := bytes.Buffer{}
+	sink := redis.NewJSONLSink(&dump)
+	for _, entry := range []redis.Entry{
+		{Type: "zset", Key: "zset", Values: []string{"a", "1.50"}},
+		{Type: "zset", Key: "zset", Values: []string{"b", "2"}},
+		{Type: "string", Key: "changed", Values: []string{"a"}},
+		{Type: "list", Key: "missing", Values: []string{"a"}},
+	} {
+		if err := sink.Write(ctx, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sink.Flush()
+
+	got := differences{}
+	stats, keys, err := client.DiffEntries(ctx, redis.NewJSONLSource(&dump), redis.ScanOptions{BatchSize: 1}, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extra, err := client.DiffExtra(ctx, redis.ScanOptions{Match: "*", Count: 10}, keys.Exists, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (redis.DiffStats{Keys: 3, Missing: 1, Changed: 1}) || extra != 1 {
+		t.Errorf("Expected 3 keys, 1 missing, 1 changed and 1 extra returned %+v %v.\n", stats, extra)
+	}
+	expected := differences{
+		{Key: "changed", Status: "changed", Type: "string", TargetType: "string"},
+		{Key: "extra", Status: "extra"},
+		{Key: "missing", Status: "missing", Type: "list"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected differences %+v returned %+v.\n", expected, got)
+	}
+}
@@ -0,0 +1,196 @@
+package redis
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	redis "github.com/go-redis/redis"
+
+	"github.com/keenangebze/go/pipeline"
+)
+
+// Entry is a redis key with its value.
+type Entry struct {
+	// Type is the redis type of the key: string, list, zset, hash, set or stream.
+	Type string
+	Key  string
+	// Values is the value of the key depending on its type:
+	//
+	//	string  [value]
+	//	list    [element...]
+	//	zset    [member, score...]
+	//	hash    [field, value...]
+	//	set     [member...]
+	//	stream  [id, ["field","value"]...], see encodeStreamFields
+	//
+	// A big value may be delivered as several consecutive entries of the same key, each holding a chunk of the elements.
+	Values []string
+	// Truncated marks the last chunk of a value cut by a maximum number of elements, e.g. ListOptions.MaxElements.
+	Truncated bool
+	// TTL is the time to live left when the key was read, 0 means no expiry or not read, see DeleteOptions.Backup.
+	TTL time.Duration
+}
+
+// fetch reads the result of a command queued in a pipeline once the pipeline is executed.
+type fetch func() ([]string, error)
+
+// fetchPage reads the values of a page of keys using the pipeline and writes them to the sink.
+type fetchPage func(ctx context.Context, it *KeyIterator, pipe redis.Pipeliner, keys []string, sink pipeline.Sink[Entry]) error
+
+// dumpPage is a page of keys with its entries once fetched, and the cursor to resume from once it is written.
+type dumpPage struct {
+	keys    []string
+	cursor  uint64
+	entries []Entry
+}
+
+// dumpPages fetches each page of keys selected by opts with fetch, and writes the entries to the sink.
+//
+// With opts.Workers above 1, a single goroutine scans the keys while the workers fetch the pages concurrently,
+// each with its own pipeline on a pooled connection, and the entries are written in the scan order.
+// The entries of a page, including the chunks of a big value, are then held in memory until the page is written.
+func (c *Client) dumpPages(ctx context.Context, opts ScanOptions, sink pipeline.Sink[Entry], fetch fetchPage) error {
+	if opts.Workers <= 1 {
+		pipe := c.redis.Pipeline()
+		defer pipe.Close()
+		it := c.ScanKeys(ctx, opts)
+		for it.Next() {
+			if err := fetch(ctx, it, pipe, it.Keys(), sink); err != nil {
+				return err
+			}
+		}
+		return it.Err()
+	}
+
+	// the cursor is only reported once the pages before it are written, not once scanned
+	progress := opts.Progress
+	opts.Progress = nil
+	it := c.ScanKeys(ctx, opts)
+	source := pipeline.SourceFunc[dumpPage](func(ctx context.Context) (dumpPage, error) {
+		if !it.Next() {
+			if err := it.Err(); err != nil {
+				return dumpPage{}, err
+			}
+			return dumpPage{}, io.EOF
+		}
+		return dumpPage{keys: it.Keys(), cursor: it.Cursor()}, nil
+	})
+	stage := pipeline.StageFunc[dumpPage, dumpPage](func(ctx context.Context, page dumpPage) (dumpPage, error) {
+		pipe := c.redis.Pipeline()
+		defer pipe.Close()
+		err := fetch(ctx, it, pipe, page.keys, pipeline.SinkFunc[Entry](func(ctx context.Context, entry Entry) error {
+			page.entries = append(page.entries, entry)
+			return nil
+		}))
+		return page, err
+	})
+	writer := pipeline.SinkFunc[dumpPage](func(ctx context.Context, page dumpPage) error {
+		for _, entry := range page.entries {
+			if err := sink.Write(ctx, entry); err != nil {
+				return err
+			}
+		}
+		if progress != nil {
+			atomic.StoreUint64(progress, page.cursor)
+		}
+		return nil
+	})
+	return pipeline.Run[dumpPage, dumpPage](ctx, source, stage, writer, pipeline.Options{Workers: opts.Workers, Ordered: true})
+}
+
+// dumpPipelined dumps each page of keys using a single pipeline.
+// queue adds the command reading the value of a key to the pipeline, it returns nil to skip the key.
+func (c *Client) dumpPipelined(ctx context.Context, opts ScanOptions, sink pipeline.Sink[Entry], keyType string, queue func(pipe redis.Pipeliner, key string) fetch) error {
+	return c.dumpPages(ctx, opts, sink, func(ctx context.Context, it *KeyIterator, pipe redis.Pipeliner, keys []string, sink pipeline.Sink[Entry]) error {
+		fetches := make([]fetch, len(keys))
+		for i, key := range keys {
+			fetches[i] = queue(pipe, key)
+		}
+		if err := it.wait(len(keys)); err != nil {
+			return err
+		}
+		if _, err := pipe.Exec(); err != nil && !isRedisError(err) {
+			return err
+		}
+
+		for i, key := range keys {
+			if fetches[i] == nil {
+				continue
+			}
+			values, err := fetches[i]()
+			if err == redis.Nil {
+				// deleted since scanned
+				continue
+			}
+			if err != nil {
+				if err := keyError(opts.OnError, key, err); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := sink.Write(ctx, Entry{Type: keyType, Key: key, Values: values}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// dumpElements dumps each key by reading its elements page by page, so a big value does not block redis.
+// Each page is written as an Entry chunk of the key.
+func (c *Client) dumpElements(ctx context.Context, opts ScanOptions, sink pipeline.Sink[Entry], keyType string, iterate func(key string, fn func(values []string) error) error) error {
+	return c.dumpPages(ctx, opts, sink, func(ctx context.Context, it *KeyIterator, pipe redis.Pipeliner, keys []string, sink pipeline.Sink[Entry]) error {
+		for _, key := range keys {
+			err := iterate(key, func(values []string) error {
+				if len(values) == 0 {
+					return nil
+				}
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if err := it.wait(1); err != nil {
+					return err
+				}
+				return sink.Write(ctx, Entry{Type: keyType, Key: key, Values: values})
+			})
+			if err == nil {
+				continue
+			}
+			if !isRedisError(err) {
+				return err
+			}
+			if err := keyError(opts.OnError, key, err); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// iterateElements iterates the elements of a single key using an incremental scan (HSCAN, SSCAN or ZSCAN)
+// until the cursor returns to 0, calling fn with each page of elements.
+func iterateElements(scan func(key string, cursor uint64, match string, count int64) *redis.ScanCmd, key string, count int64, fn func(elements []string) error) error {
+	var cursor uint64
+	for {
+		elements, nextCursor, err := scan(key, cursor, "", count).Result()
+		if err != nil {
+			return err
+		}
+		if err := fn(elements); err != nil {
+			return err
+		}
+		if nextCursor == 0 {
+			return nil
+		}
+		cursor = nextCursor
+	}
+}
+
+// isRedisError reports whether err is an error reply from redis such as WRONGTYPE,
+// as opposed to a connection error. go-redis does not export its error reply type, which is the type of redis.Nil.
+func isRedisError(err error) bool {
+	return reflect.TypeOf(err) == reflect.TypeOf(redis.Nil)
+}
@@ -0,0 +1,184 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	redis "github.com/go-redis/redis"
+
+	"github.com/keenangebze/go/pipeline"
+)
+
+// ErrUnsupportedType thrown if the value of a key cannot be read, e.g. the type of a redis module.
+var ErrUnsupportedType = errors.New("unsupported type")
+
+// DumpAll dumps the keys of any type selected by opts.
+//
+// The TYPE and the length of each key are pipelined first, then the value is fetched with the command of its type:
+// GET, LRANGE, ZRANGE WITHSCORES, HGETALL (sorted by field), SMEMBERS or XRANGE.
+// The values of more than opts.Count elements are read page by page instead, with LRANGE, SSCAN, ZSCAN, HSCAN
+// or XRANGE, and streamed as Entry chunks so a big value does not block redis nor fill the memory.
+// This allows a mixed keyspace to be exported in one pass.
+func (c *Client) DumpAll(ctx context.Context, opts ScanOptions, sink pipeline.Sink[Entry]) error {
+	return c.dumpPages(ctx, opts, sink, func(ctx context.Context, it *KeyIterator, pipe redis.Pipeliner, keys []string, sink pipeline.Sink[Entry]) error {
+		return c.dumpTyped(ctx, it, pipe, keys, sink, false, func(key string, err error) error {
+			return keyError(it.opts.OnError, key, err)
+		})
+	})
+}
+
+// dumpTyped writes the value of the keys of any type to the sink, using three pipelines: TYPE, the length then the value.
+// The big values are read chunk by chunk, see DumpAll.
+// withTTL reads the PTTL of the keys along their type, as Entry.TTL of every chunk.
+// onError is called with the error of a single key, see keyError.
+func (c *Client) dumpTyped(ctx context.Context, it *KeyIterator, pipe redis.Pipeliner, keys []string, sink pipeline.Sink[Entry], withTTL bool, onError func(key string, err error) error) error {
+	count := it.opts.Count
+	if count <= 0 {
+		count = defaultPageSize
+	}
+
+	// get the type of each key
+	types := make([]*redis.StatusCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		types[i] = pipe.Type(key)
+		if withTTL {
+			ttls[i] = pipe.PTTL(key)
+		}
+	}
+	if err := it.wait(len(keys)); err != nil {
+		return err
+	}
+	if _, err := pipe.Exec(); err != nil && !isRedisError(err) {
+		return err
+	}
+	keyTypes := make([]string, len(keys))
+	for i := range keys {
+		keyTypes[i] = types[i].Val()
+	}
+	lengths, err := readLengths(it.wait, pipe, keys, keyTypes)
+	if err != nil {
+		return err
+	}
+
+	// get the small values using the command of their type
+	fetches := make([]fetch, len(keys))
+	for i, key := range keys {
+		if lengths[i] <= count {
+			fetches[i] = typedValue(pipe, keyTypes[i], key)
+		}
+	}
+	if err := it.wait(len(keys)); err != nil {
+		return err
+	}
+	if _, err := pipe.Exec(); err != nil && !isRedisError(err) {
+		return err
+	}
+
+	for i, key := range keys {
+		entry := Entry{Type: keyTypes[i], Key: key}
+		if withTTL && ttls[i].Val() > 0 {
+			entry.TTL = ttls[i].Val()
+		}
+		err := types[i].Err()
+		switch {
+		case err != nil:
+		case lengths[i] > count:
+			err = c.iterateValue(entry.Type, key, count, func(values []string) error {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if err := it.wait(1); err != nil {
+					return err
+				}
+				chunk := entry
+				chunk.Values = values
+				return sink.Write(ctx, chunk)
+			})
+			if err != nil && !isRedisError(err) {
+				return err
+			}
+		case fetches[i] == nil:
+			if err = unsupportedType(entry.Type); err == nil {
+				// deleted since scanned
+				continue
+			}
+		default:
+			if entry.Values, err = fetches[i](); err == nil {
+				if err := sink.Write(ctx, entry); err != nil {
+					return err
+				}
+			}
+		}
+		if err == nil || err == redis.Nil {
+			continue
+		}
+		if err := onError(key, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// typedValue queues the command reading the value of the key in the pipeline.
+// It returns nil for the key that does not exist (type "none") or has an unknown type, see unsupportedType.
+func typedValue(pipe redis.Pipeliner, keyType, key string) fetch {
+	switch keyType {
+	case "string":
+		cmd := pipe.Get(key)
+		return func() ([]string, error) {
+			value, err := cmd.Result()
+			return []string{value}, err
+		}
+	case "list":
+		return pipe.LRange(key, 0, -1).Result
+	case "set":
+		return pipe.SMembers(key).Result
+	case "zset":
+		cmd := pipe.ZRangeWithScores(key, 0, -1)
+		return func() ([]string, error) {
+			members, err := cmd.Result()
+			return zValues(members), err
+		}
+	case "hash":
+		cmd := pipe.HGetAll(key)
+		return func() ([]string, error) {
+			hash, err := cmd.Result()
+			if err != nil {
+				return nil, err
+			}
+			fields := make([]string, 0, len(hash))
+			for field := range hash {
+				fields = append(fields, field)
+			}
+			sort.Strings(fields)
+			values := make([]string, 0, len(hash)*2)
+			for _, field := range fields {
+				values = append(values, field, hash[field])
+			}
+			return values, nil
+		}
+	case "stream":
+		cmd := pipe.Do("XRANGE", key, "-", "+")
+		return func() ([]string, error) {
+			reply, err := cmd.Result()
+			if err != nil {
+				return nil, err
+			}
+			return streamValues(reply)
+		}
+	}
+	return nil
+}
+
+// unsupportedType returns ErrUnsupportedType for a type typedValue cannot read,
+// nil for a type it reads or a key which does not exist.
+func unsupportedType(keyType string) error {
+	switch keyType {
+	case "none", "", "string", "list", "set", "zset", "hash", "stream":
+		return nil
+	}
+	return fmt.Errorf("%w %q", ErrUnsupportedType, keyType)
+}
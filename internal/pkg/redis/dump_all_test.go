@@ -0,0 +1,115 @@
+package redis_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/keenangebze/go/internal/pkg/redis"
+	"github.com/keenangebze/go/pipeline"
+)
+
+func ExampleClient_DumpAll() {
+	s, _ := miniredis.Run()
+	defer s.Close()
+	s.Set("name", "tokopedia")
+	s.RPush("queue", "a", "b,c")
+	s.ZAdd("rank", 1.5, "x")
+	s.HSet("shop", "name", "toko", "city", "jakarta")
+	s.SAdd("tags", "one")
+	s.XAdd("events", "1-1", []string{"action", "buy"})
+
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+	sink := redis.NewCSVSink(os.Stdout, true)
+	opts := redis.ScanOptions{Keys: []string{"name", "queue", "rank", "shop", "tags", "events", "missing"}}
+	client.DumpAll(context.Background(), opts, sink)
+	sink.Flush()
+
+	// Output:
+	// string,name,tokopedia
+	// list,queue,a
+	// list,queue,"b,c"
+	// zset,rank,x,1.5
+	// hash,shop,city,jakarta
+	// hash,shop,name,toko
+	// set,tags,one
+	// stream,events,1-1,"[""action"",""buy""]"
+}
+
+// TestDumpWorkers asserts the pages fetched concurrently are written in the scan order.
+func TestDumpWorkers(t *testing.T) {
+	s := miniredis.RunT(t)
+	keys := make([]string, 0, 500)
+	for i := 0; i < 500; i++ {
+		key := fmt.Sprintf("key:%v", i)
+		switch i % 3 {
+		case 0:
+			s.Set(key, key)
+		case 1:
+			s.RPush(key, "a", "b", "c")
+		default:
+			s.HSet(key, "field", key)
+		}
+		keys = append(keys, key)
+	}
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+
+	dump := func(workers int) (string, uint64) {
+		out := bytes.Buffer{}
+		sink := redis.NewCSVSink(&out, true)
+		progress := uint64(1)
+		opts := redis.ScanOptions{Keys: keys, BatchSize: 7, Workers: workers, Progress: &progress}
+		if err := client.DumpAll(context.Background(), opts, sink); err != nil {
+			t.Fatal(err)
+		}
+		sink.Flush()
+		return out.String(), progress
+	}
+	expected, _ := dump(1)
+	if dumped, progress := dump(8); dumped != expected || progress != 0 {
+		t.Fatalf("Expected the same dump as a single worker returned %v lines, progress %v.\n", strings.Count(dumped, "\n"), progress)
+	}
+}
+
+// TestDumpAllChunks asserts the values of more than Count elements are dumped chunk by chunk.
+func TestDumpAllChunks(t *testing.T) {
+	s := miniredis.RunT(t)
+	s.RPush("queue", "a", "b", "c", "d", "e")
+	s.HSet("shop", "name", "toko", "city", "jakarta", "zip", "10110")
+	s.SAdd("tags", "one", "two")
+
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+	chunks := map[string]int{}
+	values := map[string][]string{}
+	sink := pipeline.SinkFunc[redis.Entry](func(ctx context.Context, entry redis.Entry) error {
+		// miniredis ignores the COUNT of the SCAN family, only the LRANGE pages are bounded
+		if entry.Type == "list" && len(entry.Values) > 2 {
+			t.Errorf("Expected chunks of at most 2 elements returned %v.\n", entry)
+		}
+		chunks[entry.Key]++
+		values[entry.Key] = append(values[entry.Key], entry.Values...)
+		return nil
+	})
+	opts := redis.ScanOptions{Keys: []string{"queue", "shop", "tags"}, Count: 2}
+	if err := client.DumpAll(context.Background(), opts, sink); err != nil {
+		t.Fatal(err)
+	}
+
+	if chunks["queue"] != 3 || strings.Join(values["queue"], ",") != "a,b,c,d,e" {
+		t.Errorf("Expected the list in 3 chunks returned %v chunks of %v.\n", chunks["queue"], values["queue"])
+	}
+	if len(values["shop"]) != 6 {
+		t.Errorf("Expected the 3 fields of the hash returned %v.\n", values["shop"])
+	}
+	if chunks["tags"] != 1 {
+		t.Errorf("Expected the small set in a single entry returned %v chunks.\n", chunks["tags"])
+	}
+}
@@ -0,0 +1,18 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/keenangebze/go/pipeline"
+)
+
+// DumpHashes dumps the fields of the hash keys selected by opts.
+//
+// The fields are read incrementally using HSCAN so a big hash does not block redis,
+// each page is written as an Entry chunk of the key.
+func (c *Client) DumpHashes(ctx context.Context, opts ScanOptions, sink pipeline.Sink[Entry]) error {
+	return c.dumpElements(ctx, opts, sink, "hash", func(key string, fn func(values []string) error) error {
+		// HSCAN returns field and value alternately
+		return iterateElements(c.redis.HScan, key, opts.Count, fn)
+	})
+}
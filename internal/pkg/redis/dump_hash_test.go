@@ -0,0 +1,78 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/keenangebze/go/internal/pkg/redis"
+	"github.com/keenangebze/go/pipeline"
+)
+
+func ExampleClient_DumpHashes() {
+	s, _ := miniredis.Run()
+	defer s.Close()
+	s.HSet("shop:1", "name", "toko", "city", "jakarta")
+	s.HSet("shop:2", "name", "warung")
+	s.Set("other", "value")
+
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+	sink := redis.NewCSVSink(os.Stdout, false)
+	client.DumpHashes(context.Background(), redis.ScanOptions{Match: "shop:*", Count: 10}, sink)
+	sink.Flush()
+
+	// Unordered output:
+	// shop:1,city,jakarta
+	// shop:1,name,toko
+	// shop:2,name,warung
+}
+
+func ExampleClient_DumpSets() {
+	s, _ := miniredis.Run()
+	defer s.Close()
+	s.SAdd("tags", "one", "two")
+
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+	sink := redis.NewCSVSink(os.Stdout, false)
+	client.DumpSets(context.Background(), redis.ScanOptions{Keys: []string{"tags"}, Count: 10}, sink)
+	sink.Flush()
+
+	// Unordered output:
+	// tags,one
+	// tags,two
+}
+
+// TestDumpWrongType asserts the key of the wrong type is handed to OnError.
+func TestDumpWrongType(t *testing.T) {
+	s := miniredis.RunT(t)
+	s.Set("string", "value")
+	s.HSet("hash", "field", "value")
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+
+	errStop := errors.New("stop")
+	var dumped []string
+	sink := pipeline.SinkFunc[redis.Entry](func(ctx context.Context, entry redis.Entry) error {
+		dumped = append(dumped, entry.Key)
+		return nil
+	})
+	opts := redis.ScanOptions{Keys: []string{"hash", "string"}, Count: 10}
+
+	opts.OnError = func(err error) error { return nil }
+	if err := client.DumpHashes(context.Background(), opts, sink); err != nil {
+		t.Fatal(err)
+	}
+	if len(dumped) != 1 || dumped[0] != "hash" {
+		t.Fatalf("Expected only hash dumped returned %v.\n", dumped)
+	}
+
+	opts.OnError = func(err error) error { return errStop }
+	if err := client.DumpStrings(context.Background(), opts, sink); !errors.Is(err, errStop) {
+		t.Fatalf("Expected errStop returned %v.\n", err)
+	}
+}
@@ -0,0 +1,142 @@
+package redis
+
+import (
+	"context"
+
+	redis "github.com/go-redis/redis"
+
+	"github.com/keenangebze/go/pipeline"
+)
+
+// ListOptions bounds the list values read by DumpLists.
+type ListOptions struct {
+	// ChunkThreshold is the length above which a list is read with LRANGE pages of ScanOptions.Count elements,
+	// streamed as Entry chunks, instead of a single pipelined LRANGE. Default to ScanOptions.Count.
+	ChunkThreshold int64
+	// MaxElements is the maximum number of elements dumped for each key, 0 means no limit.
+	// The last chunk of a list cut by MaxElements is marked as Entry.Truncated.
+	MaxElements int64
+}
+
+// defaultPageSize is the number of elements of a page when ScanOptions.Count is not set.
+const defaultPageSize = 1000
+
+// DumpLists dumps the elements of the list keys selected by opts.
+//
+// The LLEN of each key is pipelined first. The lists up to list.ChunkThreshold elements are then read
+// using a single pipelined LRANGE, the longer ones are read page by page so a big list does not block redis
+// nor fill the memory.
+func (c *Client) DumpLists(ctx context.Context, opts ScanOptions, list ListOptions, sink pipeline.Sink[Entry]) error {
+	pageSize := opts.Count
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if list.ChunkThreshold <= 0 {
+		list.ChunkThreshold = pageSize
+	}
+	return c.dumpPages(ctx, opts, sink, func(ctx context.Context, it *KeyIterator, pipe redis.Pipeliner, keys []string, sink pipeline.Sink[Entry]) error {
+		// get the length of each key
+		lengths := make([]*redis.IntCmd, len(keys))
+		for i, key := range keys {
+			lengths[i] = pipe.LLen(key)
+		}
+		if err := it.wait(len(keys)); err != nil {
+			return err
+		}
+		if _, err := pipe.Exec(); err != nil && !isRedisError(err) {
+			return err
+		}
+
+		// get the small lists at once
+		ranges := make([]*redis.StringSliceCmd, len(keys))
+		for i, key := range keys {
+			length := lengths[i].Val()
+			if lengths[i].Err() == nil && length > 0 && length <= list.ChunkThreshold {
+				ranges[i] = pipe.LRange(key, 0, list.end(length))
+			}
+		}
+		if err := it.wait(len(keys)); err != nil {
+			return err
+		}
+		if _, err := pipe.Exec(); err != nil && !isRedisError(err) {
+			return err
+		}
+
+		for i, key := range keys {
+			length, err := lengths[i].Result()
+			switch {
+			case err != nil:
+			case length == 0:
+				// deleted since scanned
+				continue
+			case ranges[i] != nil:
+				var values []string
+				if values, err = ranges[i].Result(); err == nil {
+					err = sink.Write(ctx, Entry{Type: "list", Key: key, Values: values, Truncated: list.truncated(length)})
+				}
+			default:
+				err = c.iterateList(ctx, it, key, length, pageSize, list, sink)
+			}
+			if err == nil {
+				continue
+			}
+			if !isRedisError(err) {
+				return err
+			}
+			if err := keyError(opts.OnError, key, err); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// iterateList streams the elements of a big list of length elements with LRANGE pages of pageSize elements,
+// up to list.MaxElements.
+func (c *Client) iterateList(ctx context.Context, it *KeyIterator, key string, length, pageSize int64, list ListOptions, sink pipeline.Sink[Entry]) error {
+	end := length
+	if list.truncated(length) {
+		end = list.MaxElements
+	}
+	for start := int64(0); start < end; start += pageSize {
+		stop := start + pageSize - 1
+		if stop >= end {
+			stop = end - 1
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := it.wait(1); err != nil {
+			return err
+		}
+		values, err := c.redis.LRange(key, start, stop).Result()
+		if err != nil {
+			return err
+		}
+		// the list may have been trimmed since LLEN
+		last := stop == end-1 || int64(len(values)) <= stop-start
+		truncated := last && list.truncated(length)
+		if len(values) > 0 || truncated {
+			if err := sink.Write(ctx, Entry{Type: "list", Key: key, Values: values, Truncated: truncated}); err != nil {
+				return err
+			}
+		}
+		if last {
+			return nil
+		}
+	}
+	return nil
+}
+
+// end returns the index of the last element to read of a list of length elements.
+func (l ListOptions) end(length int64) int64 {
+	if l.MaxElements > 0 && length > l.MaxElements {
+		return l.MaxElements - 1
+	}
+	return -1
+}
+
+// truncated reports whether a list of length elements is cut by MaxElements.
+func (l ListOptions) truncated(length int64) bool {
+	return l.MaxElements > 0 && length > l.MaxElements
+}
@@ -0,0 +1,70 @@
+package redis_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/keenangebze/go/internal/pkg/redis"
+	"github.com/keenangebze/go/pipeline"
+)
+
+func ExampleClient_DumpLists() {
+	s, _ := miniredis.Run()
+	defer s.Close()
+	s.RPush("queue", "a", "b", "c", "d", "e")
+
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+	sink := redis.NewCSVSink(os.Stdout, false)
+	// read in pages of 2 elements, cut after 3 elements
+	client.DumpLists(context.Background(), redis.ScanOptions{Match: "*", Count: 2}, redis.ListOptions{MaxElements: 3}, sink)
+	sink.Flush()
+
+	// Output:
+	// queue,a
+	// queue,b
+	// queue,c
+	// queue
+}
+
+func TestDumpListsChunked(t *testing.T) {
+	s := miniredis.RunT(t)
+	for i := 0; i < 25; i++ {
+		s.RPush("big", fmt.Sprint(i))
+	}
+	s.RPush("small", "x", "y")
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+
+	cases := []struct {
+		name     string
+		list     redis.ListOptions
+		expected string
+	}{
+		{"chunked", redis.ListOptions{ChunkThreshold: 5}, "[big:10 big:10 big:5 small:2]"},
+		{"capped", redis.ListOptions{ChunkThreshold: 5, MaxElements: 12}, "[big:10 big:2:truncated small:2]"},
+		{"pipelined", redis.ListOptions{ChunkThreshold: 100, MaxElements: 12}, "[big:12:truncated small:2]"},
+	}
+	for _, c := range cases {
+		var chunks []string
+		sink := pipeline.SinkFunc[redis.Entry](func(ctx context.Context, entry redis.Entry) error {
+			chunk := fmt.Sprint(entry.Key, ":", len(entry.Values))
+			if entry.Truncated {
+				chunk += ":truncated"
+			}
+			chunks = append(chunks, chunk)
+			return nil
+		})
+		err := client.DumpLists(context.Background(), redis.ScanOptions{Keys: []string{"big", "small", "missing"}, Count: 10}, c.list, sink)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(chunks) != c.expected {
+			t.Errorf("%v: expected %v returned %v.\n", c.name, c.expected, chunks)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/keenangebze/go/pipeline"
+)
+
+// DumpSets dumps the members of the set keys selected by opts.
+//
+// The members are read incrementally using SSCAN so a big set does not block redis,
+// each page is written as an Entry chunk of the key.
+func (c *Client) DumpSets(ctx context.Context, opts ScanOptions, sink pipeline.Sink[Entry]) error {
+	return c.dumpElements(ctx, opts, sink, "set", func(key string, fn func(values []string) error) error {
+		return iterateElements(c.redis.SScan, key, opts.Count, fn)
+	})
+}
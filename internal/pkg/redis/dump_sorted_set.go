@@ -0,0 +1,108 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	redis "github.com/go-redis/redis"
+
+	"github.com/keenangebze/go/pipeline"
+)
+
+// ZRangeOptions filters and orders the sorted set members of DumpSortedSets.
+type ZRangeOptions struct {
+	// Min and Max are the score range in ZRANGEBYSCORE syntax, e.g. "-inf", "10" or "(10" for exclusive.
+	// Empty means unbounded.
+	Min, Max string
+	// Limit is the maximum number of members dumped for each key, 0 means no limit.
+	Limit int64
+	// Reverse orders the members from the highest score.
+	Reverse bool
+}
+
+// DumpSortedSets dumps the members and scores of the sorted set keys selected by opts.
+//
+// Without zRange the members are read incrementally using ZSCAN so a big sorted set does not block redis,
+// the members are then not ordered by score. Otherwise they are read in score order using
+// ZRANGEBYSCORE (or ZREVRANGEBYSCORE) paginated by opts.Count from the last score read. Each page is written as an Entry chunk of the key.
+func (c *Client) DumpSortedSets(ctx context.Context, opts ScanOptions, zRange ZRangeOptions, sink pipeline.Sink[Entry]) error {
+	return c.dumpElements(ctx, opts, sink, "zset", func(key string, fn func(values []string) error) error {
+		if zRange == (ZRangeOptions{}) {
+			// ZSCAN returns member and score alternately
+			return iterateElements(c.redis.ZScan, key, opts.Count, fn)
+		}
+		return c.iterateSortedSet(key, opts.Count, zRange, fn)
+	})
+}
+
+// iterateSortedSet iterates the members of a sorted set within the zRange score range,
+// pageSize members at a time, until zRange.Limit members are read.
+//
+// Each page starts at the last score read, skipping the members of that score already read,
+// as the LIMIT offset from the start of the range would cost O(N) per page and O(N²) for the whole set.
+func (c *Client) iterateSortedSet(key string, pageSize int64, zRange ZRangeOptions, fn func(values []string) error) error {
+	by := redis.ZRangeBy{Min: zRange.Min, Max: zRange.Max}
+	if by.Min == "" {
+		by.Min = "-inf"
+	}
+	if by.Max == "" {
+		by.Max = "+inf"
+	}
+	zRangeBy := c.redis.ZRangeByScoreWithScores
+	if zRange.Reverse {
+		zRangeBy = c.redis.ZRevRangeByScoreWithScores
+	}
+
+	var read int64
+	var last float64
+	for {
+		by.Count = pageSize
+		if zRange.Limit > 0 && zRange.Limit-read < by.Count {
+			by.Count = zRange.Limit - read
+		}
+		if by.Count <= 0 {
+			return nil
+		}
+		members, err := zRangeBy(key, by).Result()
+		if err != nil {
+			return err
+		}
+		if err := fn(zValues(members)); err != nil {
+			return err
+		}
+		if int64(len(members)) < by.Count {
+			return nil
+		}
+
+		score := members[len(members)-1].Score
+		var tied int64
+		for i := len(members) - 1; i >= 0 && members[i].Score == score; i-- {
+			tied++
+		}
+		// a page of a single score continues the members of the previous page with that score
+		if read > 0 && score == last {
+			by.Offset += tied
+		} else {
+			by.Offset = tied
+		}
+		read += int64(len(members))
+		last = score
+		// as redis reads the infinite scores, e.g. "+inf"
+		bound := strings.ToLower(strconv.FormatFloat(score, 'g', -1, 64))
+		if zRange.Reverse {
+			by.Max = bound
+		} else {
+			by.Min = bound
+		}
+	}
+}
+
+// zValues formats the sorted set members as member and score alternately.
+func zValues(members []redis.Z) []string {
+	values := make([]string, 0, len(members)*2)
+	for _, z := range members {
+		values = append(values, z.Member.(string), strconv.FormatFloat(z.Score, 'f', -1, 64))
+	}
+	return values
+}
@@ -0,0 +1,86 @@
+package redis_test
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/keenangebze/go/internal/pkg/redis"
+	"github.com/keenangebze/go/pipeline"
+)
+
+// TestDumpSortedSetsPages asserts the pages continue from the last score read, also across the members of a score.
+func TestDumpSortedSetsPages(t *testing.T) {
+	s := miniredis.RunT(t)
+	for member, score := range map[string]float64{"a": 1, "b": 1, "c": 1, "d": 1, "e": 2, "f": 2, "g": math.Inf(1)} {
+		s.ZAdd("rank", score, member)
+	}
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+
+	for _, c := range []struct {
+		zRange   redis.ZRangeOptions
+		expected string
+	}{
+		{redis.ZRangeOptions{Min: "(0"}, "[a 1 b 1 c 1 d 1 e 2 f 2 g +Inf]"},
+		{redis.ZRangeOptions{Max: "2", Limit: 5}, "[a 1 b 1 c 1 d 1 e 2]"},
+		{redis.ZRangeOptions{Reverse: true}, "[g +Inf f 2 e 2 d 1 c 1 b 1 a 1]"},
+	} {
+		// a page of 2 ends within the members of score 1, a page of 3 after
+		for _, count := range []int64{2, 3} {
+			var values []string
+			sink := pipeline.SinkFunc[redis.Entry](func(ctx context.Context, entry redis.Entry) error {
+				values = append(values, entry.Values...)
+				return nil
+			})
+			opts := redis.ScanOptions{Keys: []string{"rank"}, Count: count}
+			if err := client.DumpSortedSets(context.Background(), opts, c.zRange, sink); err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(values) != c.expected {
+				t.Errorf("%+v by %v: expected %v returned %v.\n", c.zRange, count, c.expected, values)
+			}
+		}
+	}
+}
+
+func ExampleClient_DumpSortedSets() {
+	s, _ := miniredis.Run()
+	defer s.Close()
+	for i, member := range []string{"a", "b", "c", "d", "e"} {
+		s.ZAdd("rank", float64(i)+0.5, member)
+	}
+
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+	sink := redis.NewCSVSink(os.Stdout, false)
+	opts := redis.ScanOptions{Keys: []string{"rank"}, Count: 2}
+	client.DumpSortedSets(context.Background(), opts, redis.ZRangeOptions{Min: "1", Limit: 3, Reverse: true}, sink)
+	sink.Flush()
+
+	// Output:
+	// rank,e,4.5
+	// rank,d,3.5
+	// rank,c,2.5
+}
+
+func ExampleClient_DumpSortedSets_zscan() {
+	s, _ := miniredis.Run()
+	defer s.Close()
+	s.ZAdd("rank", 1, "a")
+	s.ZAdd("rank", 2.5, "b")
+
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+	sink := redis.NewCSVSink(os.Stdout, false)
+	client.DumpSortedSets(context.Background(), redis.ScanOptions{Match: "*", Count: 10}, redis.ZRangeOptions{}, sink)
+	sink.Flush()
+
+	// Unordered output:
+	// rank,a,1
+	// rank,b,2.5
+}
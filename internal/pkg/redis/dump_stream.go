@@ -0,0 +1,199 @@
+package redis
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	redis "github.com/go-redis/redis"
+
+	"github.com/keenangebze/go/pipeline"
+)
+
+// DumpStreams dumps the entries of the stream keys selected by opts, as ID and fields pairs, see encodeStreamFields.
+//
+// The entries are read incrementally using XRANGE pages of opts.Count entries so a big stream does not block redis,
+// each page is written as an Entry chunk of the key.
+func (c *Client) DumpStreams(ctx context.Context, opts ScanOptions, sink pipeline.Sink[Entry]) error {
+	return c.dumpElements(ctx, opts, sink, "stream", func(key string, fn func(values []string) error) error {
+		return iterateStream(c.do, key, opts.Count, fn)
+	})
+}
+
+// do sends a command go-redis has no method for, as the UniversalClient has no Do.
+func (c *Client) do(args ...interface{}) *redis.Cmd {
+	cmd := redis.NewCmd(args...)
+	_ = c.redis.Process(cmd)
+	return cmd
+}
+
+// iterateStream iterates the entries of a stream with XRANGE pages of count entries, calling fn with each non empty page.
+// The next page starts after the last ID read, as the exclusive range of XRANGE needs redis 6.2.
+func iterateStream(do func(args ...interface{}) *redis.Cmd, key string, count int64, fn func(values []string) error) error {
+	if count <= 0 {
+		count = defaultPageSize
+	}
+	start := "-"
+	for {
+		reply, err := do("XRANGE", key, start, "+", "COUNT", count).Result()
+		if err != nil {
+			return err
+		}
+		values, err := streamValues(reply)
+		if err != nil {
+			return err
+		}
+		if len(values) == 0 {
+			// empty, or the last page was full
+			return nil
+		}
+		if err := fn(values); err != nil {
+			return err
+		}
+		if int64(len(values)/2) < count {
+			return nil
+		}
+		if start, err = nextStreamID(values[len(values)-2]); err != nil {
+			return err
+		}
+	}
+}
+
+// streamValues returns the ID and the encoded fields of each entry of the raw XRANGE reply.
+// The raw reply is read as go-redis returns the fields of an entry as a map, losing their order and duplicates.
+func streamValues(reply interface{}) ([]string, error) {
+	entries, ok := reply.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected XRANGE reply %T", reply)
+	}
+	values := make([]string, 0, len(entries)*2)
+	for _, item := range entries {
+		entry, _ := item.([]interface{})
+		if len(entry) != 2 {
+			return nil, fmt.Errorf("unexpected XRANGE entry %v", item)
+		}
+		id, _ := entry[0].(string)
+		list, _ := entry[1].([]interface{})
+		fields := make([]string, len(list))
+		for i, field := range list {
+			fields[i], _ = field.(string)
+		}
+		encoded, err := encodeStreamFields(fields)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, id, encoded)
+	}
+	return values, nil
+}
+
+// encodeStreamFields encodes the field and value pairs of a stream entry, in order, as a JSON array of strings,
+// e.g. ["field","value"]. If a string is not valid UTF-8, which JSON cannot hold, every string is encoded
+// in base64 instead, e.g. {"base64":["ZmllbGQ=","/w=="]}.
+func encodeStreamFields(fields []string) (string, error) {
+	var encoded []byte
+	var err error
+	if validUTF8(fields) {
+		encoded, err = json.Marshal(fields)
+	} else {
+		b64 := make([]string, len(fields))
+		for i, field := range fields {
+			b64[i] = base64.StdEncoding.EncodeToString([]byte(field))
+		}
+		encoded, err = json.Marshal(map[string][]string{"base64": b64})
+	}
+	return string(encoded), err
+}
+
+// decodeStreamFields decodes the fields encoded by encodeStreamFields.
+// The JSON object of the fields written by the older dumps, e.g. {"field":"value"}, is also accepted, in any order.
+func decodeStreamFields(encoded string) ([]string, error) {
+	var fields []string
+	if strings.HasPrefix(strings.TrimSpace(encoded), "[") {
+		err := json.Unmarshal([]byte(encoded), &fields)
+		return fields, err
+	}
+	object := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(encoded), &object); err != nil {
+		return nil, err
+	}
+	if b64, ok := object["base64"]; ok && len(object) == 1 && strings.HasPrefix(string(b64), "[") {
+		if err := json.Unmarshal(b64, &fields); err != nil {
+			return nil, err
+		}
+		for i, field := range fields {
+			decoded, err := base64.StdEncoding.DecodeString(field)
+			if err != nil {
+				return nil, err
+			}
+			fields[i] = string(decoded)
+		}
+		return fields, nil
+	}
+	// legacy object of the fields
+	for field, raw := range object {
+		var value interface{}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, err
+		}
+		if s, ok := value.(string); ok {
+			fields = append(fields, field, s)
+		} else {
+			fields = append(fields, field, string(raw))
+		}
+	}
+	return fields, nil
+}
+
+// validUTF8 reports whether every string is valid UTF-8.
+func validUTF8(values []string) bool {
+	for _, value := range values {
+		if !utf8.ValidString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// xaddArgs returns the XADD command adding the fields to the stream, in order.
+// An empty id lets redis generate it, maxLen caps the stream approximately when positive.
+func xaddArgs(stream, id string, maxLen int64, fields []string) []interface{} {
+	args := make([]interface{}, 0, len(fields)+6)
+	args = append(args, "XADD", stream)
+	if maxLen > 0 {
+		args = append(args, "MAXLEN", "~", maxLen)
+	}
+	if id == "" {
+		id = "*"
+	}
+	args = append(args, id)
+	for _, field := range fields {
+		args = append(args, field)
+	}
+	return args
+}
+
+// nextStreamID returns the smallest stream ID greater than id, e.g. 1-2 for 1-1.
+func nextStreamID(id string) (string, error) {
+	i := strings.IndexByte(id, '-')
+	if i < 0 {
+		return "", fmt.Errorf("invalid stream ID %q", id)
+	}
+	ms, err := strconv.ParseUint(id[:i], 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid stream ID %q: %w", id, err)
+	}
+	seq, err := strconv.ParseUint(id[i+1:], 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid stream ID %q: %w", id, err)
+	}
+	if seq == math.MaxUint64 {
+		return strconv.FormatUint(ms+1, 10) + "-0", nil
+	}
+	return strconv.FormatUint(ms, 10) + "-" + strconv.FormatUint(seq+1, 10), nil
+}
@@ -0,0 +1,20 @@
+package redis
+
+import (
+	"context"
+
+	redis "github.com/go-redis/redis"
+
+	"github.com/keenangebze/go/pipeline"
+)
+
+// DumpStrings dumps the value of the string keys selected by opts using GET.
+func (c *Client) DumpStrings(ctx context.Context, opts ScanOptions, sink pipeline.Sink[Entry]) error {
+	return c.dumpPipelined(ctx, opts, sink, "string", func(pipe redis.Pipeliner, key string) fetch {
+		cmd := pipe.Get(key)
+		return func() ([]string, error) {
+			value, err := cmd.Result()
+			return []string{value}, err
+		}
+	})
+}
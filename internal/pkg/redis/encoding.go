@@ -0,0 +1,144 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/keenangebze/go/pipeline"
+)
+
+// ErrUnknownFormat thrown if the encoding format is not one of Formats.
+var ErrUnknownFormat = errors.New("unknown format, must be csv, jsonl or binary")
+
+// Formats are the supported encodings of the entries:
+//
+//   - csv: one row per element, see CSVSink. The values are text, "\r\n" in values may be read back as "\n".
+//   - jsonl: one JSON object per entry with the typed value, see JSONLSink. The values must be valid UTF-8.
+//   - binary: length-prefixed fields, see BinarySink. It round-trips any value exactly.
+var Formats = []string{"csv", "jsonl", "binary"}
+
+// Encoder is a pipeline.Sink encoding the entries, Flush must be called once done.
+type Encoder interface {
+	pipeline.Sink[Entry]
+	Flush() error
+}
+
+// NewEncoder returns the Encoder of the format writing to out.
+// typed writes the type of the key in the csv format, the other formats always have it.
+func NewEncoder(format string, out io.Writer, typed bool) (Encoder, error) {
+	switch format {
+	case "csv":
+		return NewCSVSink(out, typed), nil
+	case "jsonl":
+		return NewJSONLSink(out), nil
+	case "binary":
+		return NewBinarySink(out), nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+}
+
+// NewDecoder returns the Source reading the entries encoded in the format from in.
+// keyType is the type of the keys in the csv format, or empty if the rows are typed.
+func NewDecoder(format string, in io.Reader, keyType string) (pipeline.Source[Entry], error) {
+	switch format {
+	case "csv":
+		return NewCSVSource(in, keyType), nil
+	case "jsonl":
+		return NewJSONLSource(in), nil
+	case "binary":
+		return NewBinarySource(in), nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+}
+
+// SyncWriter serializes the writes to w, e.g. to merge the output of the sinks of several nodes dumped concurrently.
+// A sink writing a big key holds it until the key ends, so each sink must be written from its own goroutine
+// and flushed once done, also on error.
+type SyncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewSyncWriter wraps w in to a SyncWriter.
+func NewSyncWriter(w io.Writer) *SyncWriter {
+	return &SyncWriter{w: w}
+}
+
+// Write implements io.Writer.
+func (s *SyncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// maxKeyBuffer is the size of the chunks of a key above which they are written as they come, see keyBuffer.
+const maxKeyBuffer = 1 << 20
+
+// keyBuffer buffers the encoded chunks of a key and writes them at once to out when the next key starts or on flush,
+// so the chunks of a key are not interleaved when several sinks share a SyncWriter.
+// Once the chunks of a key pass maxKeyBuffer, the SyncWriter is held until the key ends
+// and the chunks are written as they come, so a big key is not held in memory.
+type keyBuffer struct {
+	out  io.Writer
+	buf  bytes.Buffer
+	last string
+	held *SyncWriter
+}
+
+// start flushes the buffer if key is not the key being buffered, or if the buffer passed maxKeyBuffer.
+func (b *keyBuffer) start(key string) error {
+	if key != b.last {
+		b.last = key
+		return b.flush()
+	}
+	if b.buf.Len() < maxKeyBuffer {
+		return nil
+	}
+	if s, ok := b.out.(*SyncWriter); ok && b.held == nil {
+		s.mu.Lock()
+		b.held = s
+	}
+	return b.write()
+}
+
+// flush writes the buffer and releases the SyncWriter held for a big key.
+func (b *keyBuffer) flush() error {
+	err := b.write()
+	if b.held != nil {
+		b.held.mu.Unlock()
+		b.held = nil
+	}
+	return err
+}
+
+// write writes the buffer to out, or directly to the writer of the SyncWriter held.
+func (b *keyBuffer) write() error {
+	if b.buf.Len() == 0 {
+		return nil
+	}
+	out := b.out
+	if b.held != nil {
+		out = b.held.w
+	}
+	_, err := out.Write(b.buf.Bytes())
+	b.buf.Reset()
+	return err
+}
+
+// ensure the sinks implement Encoder
+var (
+	_ Encoder = (*CSVSink)(nil)
+	_ Encoder = (*JSONLSink)(nil)
+	_ Encoder = (*BinarySink)(nil)
+)
+
+// entrySource adapts a decoding function in to a Source.
+func entrySource(next func() (Entry, error)) pipeline.Source[Entry] {
+	return pipeline.SourceFunc[Entry](func(ctx context.Context) (Entry, error) {
+		return next()
+	})
+}
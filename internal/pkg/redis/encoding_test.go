@@ -0,0 +1,159 @@
+package redis_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/keenangebze/go/internal/pkg/redis"
+)
+
+func ExampleJSONLSink() {
+	sink := redis.NewJSONLSink(os.Stdout)
+	ctx := context.Background()
+	sink.Write(ctx, redis.Entry{Type: "list", Key: "queue", Values: []string{"a", "b,c"}})
+	sink.Write(ctx, redis.Entry{Type: "zset", Key: "rank", Values: []string{"x", "1.5"}})
+	sink.Write(ctx, redis.Entry{Type: "hash", Key: "shop", Values: []string{"name", "toko"}})
+	sink.Flush()
+
+	// Output:
+	// {"type":"list","key":"queue","value":["a","b,c"]}
+	// {"type":"zset","key":"rank","value":[{"member":"x","score":"1.5"}]}
+	// {"type":"hash","key":"shop","value":{"name":"toko"}}
+}
+
+// TestBigKeyStreamed asserts the chunks of a big key are written as they come, and not interleaved
+// with the keys of another sink sharing the SyncWriter.
+func TestBigKeyStreamed(t *testing.T) {
+	buf := bytes.Buffer{}
+	out := redis.NewSyncWriter(&buf)
+	big, small := redis.NewBinarySink(out), redis.NewBinarySink(out)
+	ctx := context.Background()
+	chunk := redis.Entry{Type: "list", Key: "big", Values: make([]string, 100)}
+	for i := range chunk.Values {
+		chunk.Values[i] = strings.Repeat("a", 1024)
+	}
+	for i := 0; i < 20; i++ {
+		big.Write(ctx, chunk)
+	}
+	if buf.Len() == 0 {
+		t.Fatal("Expected the chunks of the big key written before the flush.")
+	}
+
+	done := make(chan error)
+	go func() {
+		small.Write(ctx, redis.Entry{Type: "string", Key: "small", Values: []string{"b"}})
+		done <- small.Flush()
+	}()
+	for i := 0; i < 20; i++ {
+		big.Write(ctx, chunk)
+	}
+	if err := big.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []string
+	source := redis.NewBinarySource(&buf)
+	for {
+		entry, err := source.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) == 0 || keys[len(keys)-1] != entry.Key {
+			keys = append(keys, entry.Key)
+		}
+	}
+	if fmt.Sprint(keys) != "[big small]" {
+		t.Errorf("Expected the chunks of the big key together returned %v.\n", keys)
+	}
+}
+
+func TestBinaryCorrupted(t *testing.T) {
+	buf := bytes.Buffer{}
+	sink := redis.NewBinarySink(&buf)
+	sink.Write(context.Background(), redis.Entry{Type: "string", Key: "name", Values: []string{"toko"}})
+	sink.Flush()
+
+	source := redis.NewBinarySource(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	if _, err := source.Next(context.Background()); !errors.Is(err, redis.ErrCorruptedBinary) {
+		t.Fatalf("Expected ErrCorruptedBinary returned %v.\n", err)
+	}
+
+	// headers claiming too many fields, or more fields than the input holds
+	for _, n := range []uint64{1 << 60, 1 << 40} {
+		header := make([]byte, binary.MaxVarintLen64)
+		header = header[:binary.PutUvarint(header, n)]
+		source := redis.NewBinarySource(bytes.NewReader(append(header, 1, 'a')))
+		if _, err := source.Next(context.Background()); !errors.Is(err, redis.ErrCorruptedBinary) {
+			t.Fatalf("Expected ErrCorruptedBinary returned %v.\n", err)
+		}
+	}
+}
+
+// TestTruncated asserts the truncation marker is read back in every format.
+func TestTruncated(t *testing.T) {
+	for _, format := range redis.Formats {
+		buf := bytes.Buffer{}
+		sink, _ := redis.NewEncoder(format, &buf, true)
+		sink.Write(context.Background(), redis.Entry{Type: "list", Key: "queue", Values: []string{"a"}, Truncated: true})
+		sink.Flush()
+
+		source, _ := redis.NewDecoder(format, &buf, "")
+		var truncated bool
+		for {
+			entry, err := source.Next(context.Background())
+			if err != nil {
+				break
+			}
+			truncated = truncated || entry.Truncated
+		}
+		if !truncated {
+			t.Errorf("%v: expected the truncation marker read back.\n", format)
+		}
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	if _, err := redis.NewEncoder("xml", os.Stdout, false); !errors.Is(err, redis.ErrUnknownFormat) {
+		t.Fatalf("Expected ErrUnknownFormat returned %v.\n", err)
+	}
+	if _, err := redis.NewDecoder("xml", os.Stdin, ""); !errors.Is(err, redis.ErrUnknownFormat) {
+		t.Fatalf("Expected ErrUnknownFormat returned %v.\n", err)
+	}
+}
+
+// TestJSONLScores asserts the infinite scores are written, and the numeric scores of the older dumps are read.
+func TestJSONLScores(t *testing.T) {
+	buf := bytes.Buffer{}
+	sink := redis.NewJSONLSink(&buf)
+	if err := sink.Write(context.Background(), redis.Entry{Type: "zset", Key: "rank", Values: []string{"x", "inf", "y", "-inf"}}); err != nil {
+		t.Fatal(err)
+	}
+	sink.Flush()
+	buf.WriteString(`{"type":"zset","key":"old","value":[{"member":"z","score":1.5}]}` + "\n")
+
+	source := redis.NewJSONLSource(&buf)
+	var values []string
+	for {
+		entry, err := source.Next(context.Background())
+		if err != nil {
+			break
+		}
+		values = append(values, entry.Values...)
+	}
+	if fmt.Sprint(values) != "[x inf y -inf z 1.5]" {
+		t.Errorf("Expected the scores read back returned %v.\n", values)
+	}
+}
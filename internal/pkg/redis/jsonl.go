@@ -0,0 +1,190 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/keenangebze/go/pipeline"
+)
+
+// JSONLSink writes each entry as a JSON object in its own line, with the value typed as:
+//
+//	string  {"type":"string","key":"k","value":"v"}
+//	list    {"type":"list","key":"k","value":["a","b"]}
+//	zset    {"type":"zset","key":"k","value":[{"member":"a","score":"1.5"}]}
+//	hash    {"type":"hash","key":"k","value":{"field":"v"}}
+//	set     {"type":"set","key":"k","value":["a","b"]}
+//	stream  {"type":"stream","key":"k","value":[{"id":"1-1","values":["field","v"]}]}
+//
+// A big value may be written as several lines of the same key,
+// the last line of a truncated value, see Entry.Truncated, has "truncated":true.
+// The line of a key read with its TTL, see Entry.TTL, has "ttl_ms" in milliseconds.
+type JSONLSink struct {
+	keyBuffer
+}
+
+// NewJSONLSink returns a JSONLSink writing to out.
+func NewJSONLSink(out io.Writer) *JSONLSink {
+	return &JSONLSink{keyBuffer: keyBuffer{out: out}}
+}
+
+// jsonEntry is the JSON object of an Entry.
+type jsonEntry struct {
+	Type      string          `json:"type"`
+	Key       string          `json:"key"`
+	Value     json.RawMessage `json:"value"`
+	Truncated bool            `json:"truncated,omitempty"`
+	TTL       int64           `json:"ttl_ms,omitempty"`
+}
+
+// jsonMember is a sorted set member, the score is the string returned by redis as JSON has no infinity, e.g. "+inf".
+type jsonMember struct {
+	Member string    `json:"member"`
+	Score  jsonScore `json:"score"`
+}
+
+// jsonScore is a score written as a JSON string, it is also read from the JSON number of the older dumps.
+type jsonScore string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *jsonScore) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] != '"' {
+		var score json.Number
+		if err := json.Unmarshal(data, &score); err != nil {
+			return err
+		}
+		*s = jsonScore(score)
+		return nil
+	}
+	return json.Unmarshal(data, (*string)(s))
+}
+
+type jsonMessage struct {
+	ID     string          `json:"id"`
+	Values json.RawMessage `json:"values"`
+}
+
+// Write implements pipeline.Sink.
+func (s *JSONLSink) Write(ctx context.Context, entry Entry) error {
+	if err := s.start(entry.Key); err != nil {
+		return err
+	}
+	value, err := jsonValue(entry)
+	if err != nil {
+		return fmt.Errorf("key %q: %w", entry.Key, err)
+	}
+	line, err := json.Marshal(jsonEntry{Type: entry.Type, Key: entry.Key, Value: value, Truncated: entry.Truncated, TTL: entry.TTL.Milliseconds()})
+	if err != nil {
+		return fmt.Errorf("key %q: %w", entry.Key, err)
+	}
+	s.buf.Write(line)
+	s.buf.WriteByte('\n')
+	return nil
+}
+
+// Flush writes the buffered lines.
+func (s *JSONLSink) Flush() error {
+	return s.flush()
+}
+
+// jsonValue encodes the values of the entry depending on its type.
+func jsonValue(entry Entry) ([]byte, error) {
+	values := entry.Values
+	switch entry.Type {
+	case "string":
+		if len(values) == 0 {
+			return json.Marshal("")
+		}
+		return json.Marshal(values[len(values)-1])
+	case "zset":
+		members := make([]jsonMember, 0, len(values)/2)
+		for i := 0; i+1 < len(values); i += 2 {
+			members = append(members, jsonMember{Member: values[i], Score: jsonScore(values[i+1])})
+		}
+		return json.Marshal(members)
+	case "hash":
+		fields := make(map[string]string, len(values)/2)
+		for i := 0; i+1 < len(values); i += 2 {
+			fields[values[i]] = values[i+1]
+		}
+		return json.Marshal(fields)
+	case "stream":
+		messages := make([]jsonMessage, 0, len(values)/2)
+		for i := 0; i+1 < len(values); i += 2 {
+			messages = append(messages, jsonMessage{ID: values[i], Values: json.RawMessage(values[i+1])})
+		}
+		return json.Marshal(messages)
+	}
+	if values == nil {
+		values = []string{}
+	}
+	return json.Marshal(values)
+}
+
+// NewJSONLSource returns a Source reading the JSONL written by a JSONLSink.
+func NewJSONLSource(in io.Reader) pipeline.Source[Entry] {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, 512*1024*1024)
+	line := 0
+	return entrySource(func() (Entry, error) {
+		for scanner.Scan() {
+			line++
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			entry, err := decodeJSONEntry(scanner.Bytes())
+			if err != nil {
+				return Entry{}, fmt.Errorf("line %v: %w", line, err)
+			}
+			return entry, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return Entry{}, err
+		}
+		return Entry{}, io.EOF
+	})
+}
+
+// decodeJSONEntry decodes a line written by JSONLSink.
+func decodeJSONEntry(line []byte) (Entry, error) {
+	var j jsonEntry
+	if err := json.Unmarshal(line, &j); err != nil {
+		return Entry{}, err
+	}
+	entry := Entry{Type: j.Type, Key: j.Key, Truncated: j.Truncated, TTL: time.Duration(j.TTL) * time.Millisecond}
+	var err error
+	switch j.Type {
+	case "string":
+		var value string
+		err = json.Unmarshal(j.Value, &value)
+		entry.Values = []string{value}
+	case "zset":
+		var members []jsonMember
+		err = json.Unmarshal(j.Value, &members)
+		for _, m := range members {
+			entry.Values = append(entry.Values, m.Member, string(m.Score))
+		}
+	case "hash":
+		var fields map[string]string
+		err = json.Unmarshal(j.Value, &fields)
+		for field, value := range fields {
+			entry.Values = append(entry.Values, field, value)
+		}
+	case "stream":
+		var messages []jsonMessage
+		err = json.Unmarshal(j.Value, &messages)
+		for _, m := range messages {
+			entry.Values = append(entry.Values, m.ID, string(m.Values))
+		}
+	default:
+		err = json.Unmarshal(j.Value, &entry.Values)
+	}
+	if err != nil {
+		return Entry{}, fmt.Errorf("key %q: %w", j.Key, err)
+	}
+	return entry, nil
+}
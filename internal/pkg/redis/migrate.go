@@ -0,0 +1,258 @@
+package redis
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	redis "github.com/go-redis/redis"
+
+	"github.com/keenangebze/go/pipeline"
+)
+
+// MigrateOptions configures Migrate.
+type MigrateOptions struct {
+	// Replace overwrites the keys existing in the target (RESTORE REPLACE), otherwise they are skipped.
+	Replace bool
+	// Workers is the number of pages of keys migrated concurrently. Default to 4.
+	Workers int
+	// Checkpoint is called with the SCAN cursor to resume from, see ScanOptions.Cursor, once the keys before it
+	// are migrated. The cursor 0 is also the start of the scan, so it is called with done once the scan is complete.
+	Checkpoint func(cursor uint64, done bool) error
+}
+
+// MigrateStats counts the keys migrated.
+type MigrateStats struct {
+	Migrated int64
+	// Skipped are the keys existing in the target without MigrateOptions.Replace.
+	Skipped int64
+}
+
+// migratePage is a page of keys with the cursor to resume from once it is migrated.
+type migratePage struct {
+	keys   []string
+	cursor uint64
+}
+
+// Migrate copies the keys selected by opts to the target using DUMP and RESTORE, keeping their TTL.
+// The pages of keys are migrated concurrently by MigrateOptions.Workers, each with a pipeline of DUMP and PTTL
+// on this client and a pipeline of RESTORE on the target. Both are throttled by opts.Limits.
+//
+// The DUMP payload is only restored by a redis of the same or a newer version.
+func (c *Client) Migrate(ctx context.Context, to *Client, opts ScanOptions, migrate MigrateOptions) (MigrateStats, error) {
+	if migrate.Workers <= 0 {
+		migrate.Workers = 4
+	}
+	stats := MigrateStats{}
+	exact := opts.Keys != nil || opts.KeyReader != nil
+
+	it := c.ScanKeys(ctx, opts)
+	source := pipeline.SourceFunc[migratePage](func(ctx context.Context) (migratePage, error) {
+		if !it.Next() {
+			if err := it.Err(); err != nil {
+				return migratePage{}, err
+			}
+			return migratePage{}, io.EOF
+		}
+		return migratePage{keys: it.Keys(), cursor: it.Cursor()}, nil
+	})
+	stage := pipeline.StageFunc[migratePage, migratePage](func(ctx context.Context, page migratePage) (migratePage, error) {
+		migrated, skipped, err := c.migrateKeys(it, to, page.keys, migrate.Replace)
+		atomic.AddInt64(&stats.Migrated, migrated)
+		atomic.AddInt64(&stats.Skipped, skipped)
+		return page, err
+	})
+	// the pages are ordered so a checkpoint is only written once the pages before it are migrated
+	sink := pipeline.SinkFunc[migratePage](func(ctx context.Context, page migratePage) error {
+		if migrate.Checkpoint == nil || exact {
+			return nil
+		}
+		return migrate.Checkpoint(page.cursor, false)
+	})
+	err := pipeline.Run[migratePage, migratePage](ctx, source, stage, sink, pipeline.Options{Workers: migrate.Workers, Ordered: true})
+	if err == nil && migrate.Checkpoint != nil && !exact {
+		// every page is migrated, including when the last SCAN page is empty
+		err = migrate.Checkpoint(0, true)
+	}
+	return stats, err
+}
+
+// migrateKeys copies the keys to the target, it returns the number of keys migrated and skipped.
+func (c *Client) migrateKeys(it *KeyIterator, to *Client, keys []string, replace bool) (int64, int64, error) {
+	src := c.redis.Pipeline()
+	defer src.Close()
+	dumps := make([]*redis.StringCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		dumps[i] = src.Dump(key)
+		ttls[i] = src.PTTL(key)
+	}
+	if err := it.wait(2 * len(keys)); err != nil {
+		return 0, 0, err
+	}
+	if _, err := src.Exec(); err != nil && !isRedisError(err) {
+		return 0, 0, err
+	}
+
+	dst := to.redis.Pipeline()
+	defer dst.Close()
+	restores := make([]*redis.StatusCmd, len(keys))
+	for i, key := range keys {
+		payload, err := dumps[i].Result()
+		if err != nil {
+			// deleted since scanned, or an error reported below
+			continue
+		}
+		ttl := ttls[i].Val()
+		if ttl < 0 {
+			// no expiry
+			ttl = 0
+		}
+		if replace {
+			restores[i] = dst.RestoreReplace(key, ttl, payload)
+		} else {
+			restores[i] = dst.Restore(key, ttl, payload)
+		}
+	}
+	if err := it.wait(len(keys)); err != nil {
+		return 0, 0, err
+	}
+	if _, err := dst.Exec(); err != nil && !isRedisError(err) {
+		return 0, 0, err
+	}
+
+	var migrated, skipped int64
+	for i, key := range keys {
+		err := dumps[i].Err()
+		if err == nil {
+			err = restores[i].Err()
+		}
+		switch {
+		case err == nil:
+			migrated++
+		case err == redis.Nil:
+		case strings.HasPrefix(err.Error(), "BUSYKEY"):
+			skipped++
+		default:
+			if err := keyError(it.opts.OnError, key, err); err != nil {
+				return migrated, skipped, err
+			}
+		}
+	}
+	return migrated, skipped, nil
+}
+
+// VerifyReport compares the keys of a source with a target.
+type VerifyReport struct {
+	// Keys is the number of keys of the source, of which Missing are not in the target.
+	Keys    int64
+	Missing int64
+	// Sampled is the number of keys whose values are compared, of which Mismatched differ.
+	Sampled    int64
+	Mismatched int64
+	// Examples are some of the missing or mismatched keys.
+	Examples []string
+}
+
+// maxExamples is the number of keys kept in VerifyReport.Examples.
+const maxExamples = 10
+
+// Verify compares the keys selected by opts with the target, e.g. once migrated.
+// Every key is checked with EXISTS on the target, and a sampleRate fraction of them have their values compared,
+// read as DumpAll does and compared as Diff does.
+func (c *Client) Verify(ctx context.Context, to *Client, opts ScanOptions, sampleRate float64) (VerifyReport, error) {
+	report := VerifyReport{}
+	example := func(key string) {
+		if len(report.Examples) < maxExamples {
+			report.Examples = append(report.Examples, key)
+		}
+	}
+	// the global source is not seeded before go 1.20, it would sample the same keys on every run
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	src := c.redis.Pipeline()
+	defer src.Close()
+	dst := to.redis.Pipeline()
+	defer dst.Close()
+
+	it := c.ScanKeys(ctx, opts)
+	for it.Next() {
+		keys := it.Keys()
+		exists := make([]*redis.IntCmd, len(keys))
+		for i, key := range keys {
+			exists[i] = dst.Exists(key)
+		}
+		if err := it.wait(len(keys)); err != nil {
+			return report, err
+		}
+		if _, err := dst.Exec(); err != nil {
+			return report, err
+		}
+
+		var sampled []string
+		for i, key := range keys {
+			report.Keys++
+			if exists[i].Val() == 0 {
+				report.Missing++
+				example(key)
+				continue
+			}
+			if sampleRate > 0 && random.Float64() < sampleRate {
+				sampled = append(sampled, key)
+			}
+		}
+		if len(sampled) == 0 {
+			continue
+		}
+
+		mismatched, err := compareValues(it, src, dst, sampled)
+		if err != nil {
+			return report, err
+		}
+		report.Sampled += int64(len(sampled))
+		report.Mismatched += int64(len(mismatched))
+		for _, key := range mismatched {
+			example(key)
+		}
+	}
+	return report, it.Err()
+}
+
+// compareValues returns the keys whose type or value differ between the source and the target.
+func compareValues(it *KeyIterator, src, dst redis.Pipeliner, keys []string) ([]string, error) {
+	types, values, errs, err := typedValues(it.wait, src, keys)
+	if err != nil {
+		return nil, err
+	}
+	targetTypes, targetValues, targetErrs, err := typedValues(it.wait, dst, keys)
+	if err != nil {
+		return nil, err
+	}
+	var mismatched []string
+	for i, key := range keys {
+		if err := firstError(errs[i], targetErrs[i]); err != nil {
+			if err := keyError(it.opts.OnError, key, err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if types[i] != targetTypes[i] || !equalValues(values[i], targetValues[i]) {
+			mismatched = append(mismatched, key)
+		}
+	}
+	return mismatched, nil
+}
+
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
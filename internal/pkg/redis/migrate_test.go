@@ -0,0 +1,147 @@
+package redis_test
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+
+	"github.com/keenangebze/go/internal/pkg/redis"
+)
+
+// withDumpRestore registers a DUMP and RESTORE of the string keys, as miniredis has none.
+func withDumpRestore(s *miniredis.Miniredis) *miniredis.Miniredis {
+	s.Server().Register("DUMP", func(c *server.Peer, cmd string, args []string) {
+		value, err := s.Get(args[0])
+		if err != nil {
+			c.WriteNull()
+			return
+		}
+		c.WriteBulk("payload:" + value)
+	})
+	s.Server().Register("RESTORE", func(c *server.Peer, cmd string, args []string) {
+		key, payload := args[0], args[2]
+		if s.Exists(key) && (len(args) < 4 || !strings.EqualFold(args[3], "REPLACE")) {
+			c.WriteError("BUSYKEY Target key name already exists.")
+			return
+		}
+		s.Set(key, strings.TrimPrefix(payload, "payload:"))
+		if ttl, _ := strconv.Atoi(args[1]); ttl > 0 {
+			s.SetTTL(key, time.Duration(ttl)*time.Millisecond)
+		}
+		c.WriteOK()
+	})
+	return s
+}
+
+func TestMigrate(t *testing.T) {
+	from, to := withDumpRestore(miniredis.RunT(t)), withDumpRestore(miniredis.RunT(t))
+	for i := 0; i < 20; i++ {
+		from.Set("key:"+strconv.Itoa(i), strconv.Itoa(i))
+	}
+	from.SetTTL("key:1", time.Hour)
+	to.Set("key:2", "old")
+
+	fromClient := redis.NewClient(redis.Config{Address: from.Addr()})
+	defer fromClient.Close()
+	toClient := redis.NewClient(redis.Config{Address: to.Addr()})
+	defer toClient.Close()
+	ctx := context.Background()
+	opts := redis.ScanOptions{Match: "key:*", Count: 3, BatchSize: 2}
+
+	var checkpoints []string
+	stats, err := fromClient.Migrate(ctx, toClient, opts, redis.MigrateOptions{
+		Workers: 3,
+		Checkpoint: func(cursor uint64, done bool) error {
+			checkpoints = append(checkpoints, fmt.Sprint(cursor, done))
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (redis.MigrateStats{Migrated: 19, Skipped: 1}) {
+		t.Errorf("Expected 19 migrated and 1 skipped returned %+v.\n", stats)
+	}
+	if v, _ := to.Get("key:2"); v != "old" {
+		t.Errorf("Expected existing key skipped returned %v.\n", v)
+	}
+	if ttl := to.TTL("key:1"); ttl != time.Hour {
+		t.Errorf("Expected TTL kept returned %v.\n", ttl)
+	}
+
+	// miniredis returns every key in a single SCAN page
+	if strings.Join(checkpoints, ",") != strings.Repeat("0 false,", 10)+"0 true" {
+		t.Errorf("Expected a checkpoint per batch returned %v.\n", checkpoints)
+	}
+
+	report, err := fromClient.Verify(ctx, toClient, opts, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Keys != 20 || report.Missing != 0 || report.Sampled != 20 || report.Mismatched != 1 || report.Examples[0] != "key:2" {
+		t.Errorf("Expected key:2 mismatched returned %+v.\n", report)
+	}
+
+	stats, err = fromClient.Migrate(ctx, toClient, opts, redis.MigrateOptions{Replace: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (redis.MigrateStats{Migrated: 20}) {
+		t.Errorf("Expected 20 migrated returned %+v.\n", stats)
+	}
+	if v, _ := to.Get("key:2"); v != "2" {
+		t.Errorf("Expected existing key replaced returned %v.\n", v)
+	}
+}
+
+// TestMigrateCheckpoint asserts a SCAN page split in batches is not checkpointed as done,
+// and the scan is done once the last SCAN page is read although it is empty.
+func TestMigrateCheckpoint(t *testing.T) {
+	// SCAN 0 returns a, b, c then SCAN 7 returns no key, every key is deleted since scanned
+	srv, err := server.NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	srv.Register("SCAN", func(c *server.Peer, cmd string, args []string) {
+		c.WriteLen(2)
+		if args[0] == "0" {
+			c.WriteBulk("7")
+			c.WriteStrings([]string{"a", "b", "c"})
+			return
+		}
+		c.WriteBulk("0")
+		c.WriteStrings(nil)
+	})
+	srv.Register("DUMP", func(c *server.Peer, cmd string, args []string) {
+		c.WriteNull()
+	})
+	srv.Register("PTTL", func(c *server.Peer, cmd string, args []string) {
+		c.WriteInt(-2)
+	})
+	to := miniredis.RunT(t)
+	fromClient := redis.NewClient(redis.Config{Address: srv.Addr().String()})
+	defer fromClient.Close()
+	toClient := redis.NewClient(redis.Config{Address: to.Addr()})
+	defer toClient.Close()
+
+	var checkpoints []string
+	_, err = fromClient.Migrate(context.Background(), toClient, redis.ScanOptions{Match: "*", Count: 10, BatchSize: 2}, redis.MigrateOptions{
+		Checkpoint: func(cursor uint64, done bool) error {
+			checkpoints = append(checkpoints, fmt.Sprint(cursor, done))
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(checkpoints, ",") != "0 false,7 false,0 true" {
+		t.Errorf("Expected the scan done once the last page is read returned %v.\n", checkpoints)
+	}
+}
@@ -0,0 +1,202 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strconv"
+	"time"
+
+	redis "github.com/go-redis/redis"
+
+	"github.com/keenangebze/go/pipeline"
+)
+
+// ErrInvalidBatchSize thrown if PopulateOptions.BatchSize < 1
+var ErrInvalidBatchSize = errors.New("invalid batch size, must be greater than 0")
+
+// PopulateOptions configures how the entries are written back to redis.
+type PopulateOptions struct {
+	// TTL is set on every written key, 0 keeps the Entry.TTL read by the dump, if any.
+	TTL time.Duration
+	// Overwrite replaces the existing keys, otherwise they are skipped.
+	Overwrite bool
+	// BatchSize is the number of keys sent in a single pipeline.
+	BatchSize int
+	// DryRun only reports the action for each key without writing to redis.
+	DryRun bool
+	// OnAction is called with the action for each key, one of "create", "overwrite" or "skip".
+	OnAction func(action, key string)
+}
+
+// PopulateStats counts the keys by the action done.
+type PopulateStats struct {
+	Created     int
+	Overwritten int
+	Skipped     int
+}
+
+// Populate writes the entries read from source to redis, the inverse of the Dump methods,
+// using SET, RPUSH, ZADD, HSET, SADD or XADD depending on the entry type.
+//
+// Consecutive entries of the same key are the chunks of a big value, they are appended one by one
+// with the action decided for the first chunk, so a big value is never sent in a single command.
+// Existing keys are deleted first when overwritten so the values are not appended.
+// Keys created between the EXISTS check and the write are not detected.
+func (c *Client) Populate(ctx context.Context, source pipeline.Source[Entry], opts PopulateOptions) (PopulateStats, error) {
+	stats := PopulateStats{}
+	if opts.BatchSize < 1 {
+		return stats, ErrInvalidBatchSize
+	}
+	pipe := c.redis.Pipeline()
+	defer pipe.Close()
+
+	// action is the action of the key of the last entry written, applying to its next chunks
+	action := ""
+	flush := func(batch []populateEntry) error {
+		exists := make([]*redis.IntCmd, len(batch))
+		for i, entry := range batch {
+			if !entry.continued {
+				exists[i] = pipe.Exists(entry.Key)
+			}
+		}
+		if _, err := pipe.Exec(); err != nil {
+			return err
+		}
+
+		for i, entry := range batch {
+			existed := false
+			if !entry.continued {
+				existed = exists[i].Val() > 0
+				action = "create"
+				switch {
+				case existed && opts.Overwrite:
+					action = "overwrite"
+					stats.Overwritten++
+				case existed:
+					action = "skip"
+					stats.Skipped++
+				default:
+					stats.Created++
+				}
+				if opts.OnAction != nil {
+					opts.OnAction(action, entry.Key)
+				}
+			}
+
+			if opts.DryRun || action == "skip" {
+				continue
+			}
+			if entry.Truncated {
+				log.Println("[WARN] Truncated value, only the dumped elements are written", entry.Key)
+			}
+			if existed {
+				pipe.Del(entry.Key)
+			}
+			write(pipe, entry.Entry)
+			switch {
+			case opts.TTL > 0:
+				pipe.Expire(entry.Key, opts.TTL)
+			case entry.TTL > 0:
+				pipe.PExpire(entry.Key, entry.TTL)
+			}
+		}
+		if opts.DryRun {
+			return nil
+		}
+		_, err := pipe.Exec()
+		return err
+	}
+
+	batch := make([]populateEntry, 0, opts.BatchSize)
+	last := ""
+	for {
+		entry, err := source.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stats, err
+		}
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		if len(batch) == opts.BatchSize {
+			if err := flush(batch); err != nil {
+				return stats, err
+			}
+			batch = batch[:0]
+		}
+		batch = append(batch, populateEntry{Entry: entry, continued: entry.Key == last})
+		last = entry.Key
+	}
+	if len(batch) > 0 {
+		if err := flush(batch); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// populateEntry is an entry written by Populate, continued for the next chunks of a key.
+type populateEntry struct {
+	Entry
+	continued bool
+}
+
+// write queues the commands writing the entry in the pipeline.
+// Invalid values, e.g. a sorted set score which is not a number, are logged and skipped.
+func write(pipe redis.Pipeliner, entry Entry) {
+	key, values := entry.Key, entry.Values
+	if len(values) == 0 {
+		return
+	}
+	switch entry.Type {
+	case "string":
+		pipe.Set(key, values[len(values)-1], 0)
+	case "list":
+		pipe.RPush(key, toInterfaces(values)...)
+	case "set":
+		pipe.SAdd(key, toInterfaces(values)...)
+	case "hash":
+		fields := map[string]interface{}{}
+		for i := 0; i+1 < len(values); i += 2 {
+			fields[values[i]] = values[i+1]
+		}
+		pipe.HMSet(key, fields)
+	case "zset":
+		z := make([]redis.Z, 0, len(values)/2)
+		for i := 0; i+1 < len(values); i += 2 {
+			score, err := strconv.ParseFloat(values[i+1], 64)
+			if err != nil {
+				log.Println("[WARN] Invalid score, skipping", key, values[i], values[i+1])
+				continue
+			}
+			z = append(z, redis.Z{Score: score, Member: values[i]})
+		}
+		if len(z) > 0 {
+			pipe.ZAdd(key, z...)
+		}
+	case "stream":
+		for i := 0; i+1 < len(values); i += 2 {
+			fields, err := decodeStreamFields(values[i+1])
+			if err != nil || len(fields) == 0 || len(fields)%2 != 0 {
+				log.Println("[WARN] Invalid stream entry, skipping", key, values[i], err)
+				continue
+			}
+			pipe.Do(xaddArgs(key, values[i], 0, fields)...)
+		}
+	default:
+		log.Println("[WARN] Unsupported type", entry.Type, "of key", key)
+	}
+}
+
+// toInterfaces converts the values in to the variadic arguments of go-redis.
+func toInterfaces(values []string) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	return args
+}
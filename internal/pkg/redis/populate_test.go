@@ -0,0 +1,202 @@
+package redis_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/keenangebze/go/internal/pkg/redis"
+)
+
+// populate writes the CSV of keyType in to s.
+func populate(t *testing.T, s *miniredis.Miniredis, in, keyType string, opts redis.PopulateOptions) redis.PopulateStats {
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+	stats, err := client.Populate(context.Background(), redis.NewCSVSource(strings.NewReader(in), keyType), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stats
+}
+
+func TestPopulate(t *testing.T) {
+	s := miniredis.RunT(t)
+	s.Set("existing", "old")
+	s.RPush("existing-list", "old")
+
+	stats := populate(t, s, "existing,new\nfresh,value\nbroken\n", "string", redis.PopulateOptions{BatchSize: 1, TTL: time.Hour})
+	if stats != (redis.PopulateStats{Created: 1, Skipped: 1}) {
+		t.Errorf("Expected 1 created and 1 skipped returned %+v.\n", stats)
+	}
+	if v, _ := s.Get("existing"); v != "old" {
+		t.Errorf("Expected existing key skipped returned %v.\n", v)
+	}
+	if v, _ := s.Get("fresh"); v != "value" {
+		t.Errorf("Expected fresh key written returned %v.\n", v)
+	}
+	if ttl := s.TTL("fresh"); ttl != time.Hour {
+		t.Errorf("Expected 1h TTL returned %v.\n", ttl)
+	}
+
+	populate(t, s, "existing-list,a\nexisting-list,\"b,c\"\n", "list", redis.PopulateOptions{BatchSize: 10, Overwrite: true})
+	if v, _ := s.List("existing-list"); fmt.Sprint(v) != "[a b,c]" {
+		t.Errorf("Expected list overwritten with a and b,c returned %v.\n", v)
+	}
+
+	populate(t, s, "rank,\"x,y\"\n", "zset", redis.PopulateOptions{BatchSize: 10})
+	if v, _ := s.ZMembers("rank"); strings.Join(v, ",") != "x,y" {
+		t.Errorf("Expected sorted set x,y returned %v.\n", v)
+	}
+
+	// one row per member, the rows of a key may span the batches
+	populate(t, s, "score,x,2.5\nscore,y,1\nother,z,0\n", "zset", redis.PopulateOptions{BatchSize: 1})
+	if v, _ := s.ZMembers("score"); strings.Join(v, ",") != "y,x" {
+		t.Errorf("Expected sorted set y,x returned %v.\n", v)
+	}
+	if v, _ := s.ZScore("score", "x"); v != 2.5 {
+		t.Errorf("Expected score 2.5 returned %v.\n", v)
+	}
+}
+
+func TestPopulateChunks(t *testing.T) {
+	s := miniredis.RunT(t)
+	s.RPush("big", "old")
+	s.RPush("kept", "old")
+
+	// the chunks of a key span the batches and are appended one by one, the first chunk decides the action
+	stats := populate(t, s, "big,a\nbig,b\nbig,c\nkept,x\nkept,y\n", "list", redis.PopulateOptions{BatchSize: 2})
+	if stats != (redis.PopulateStats{Skipped: 2}) {
+		t.Errorf("Expected 2 skipped returned %+v.\n", stats)
+	}
+	if v, _ := s.List("kept"); fmt.Sprint(v) != "[old]" {
+		t.Errorf("Expected kept list skipped returned %v.\n", v)
+	}
+
+	stats = populate(t, s, "big,a\nbig,b\nbig,c\nnew,x\nnew,y\n", "list", redis.PopulateOptions{BatchSize: 2, Overwrite: true})
+	if stats != (redis.PopulateStats{Created: 1, Overwritten: 1}) {
+		t.Errorf("Expected 1 created and 1 overwritten returned %+v.\n", stats)
+	}
+	if v, _ := s.List("big"); fmt.Sprint(v) != "[a b c]" {
+		t.Errorf("Expected big list overwritten with a b c returned %v.\n", v)
+	}
+	if v, _ := s.List("new"); fmt.Sprint(v) != "[x y]" {
+		t.Errorf("Expected new list x y returned %v.\n", v)
+	}
+}
+
+func TestPopulateLegacyList(t *testing.T) {
+	s := miniredis.RunT(t)
+	in := "tags,\"a,b,c\"\nempty,\n"
+
+	populate(t, s, in, "list", redis.PopulateOptions{BatchSize: 10})
+	if v, _ := s.List("tags"); fmt.Sprint(v) != "[a,b,c]" {
+		t.Errorf("Expected a single element a,b,c returned %v.\n", v)
+	}
+
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+	source := redis.NewLegacyCSVSource(strings.NewReader(in), "list")
+	if _, err := client.Populate(context.Background(), source, redis.PopulateOptions{BatchSize: 10, Overwrite: true}); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := s.List("tags"); fmt.Sprint(v) != "[a b c]" {
+		t.Errorf("Expected the legacy elements a b c returned %v.\n", v)
+	}
+	if s.Exists("empty") {
+		t.Errorf("Expected no empty list written.\n")
+	}
+}
+
+func TestPopulateDryRun(t *testing.T) {
+	s := miniredis.RunT(t)
+	s.Set("existing", "old")
+
+	var actions []string
+	populate(t, s, "existing,new\nfresh,value\n", "string", redis.PopulateOptions{
+		BatchSize: 10,
+		DryRun:    true,
+		OnAction: func(action, key string) {
+			actions = append(actions, action+" "+key)
+		},
+	})
+	if fmt.Sprint(actions) != "[skip existing create fresh]" {
+		t.Errorf("Expected skip existing and create fresh returned %v.\n", actions)
+	}
+	if v, _ := s.Get("existing"); v != "old" {
+		t.Errorf("Expected existing key untouched returned %v.\n", v)
+	}
+	if s.Exists("fresh") {
+		t.Errorf("Expected fresh key not written.\n")
+	}
+}
+
+// TestRoundTrip asserts a typed dump is restored as is in every format.
+func TestRoundTrip(t *testing.T) {
+	for _, format := range redis.Formats {
+		t.Run(format, func(t *testing.T) {
+			testRoundTrip(t, format)
+		})
+	}
+}
+
+func testRoundTrip(t *testing.T, format string) {
+	from, to := miniredis.RunT(t), miniredis.RunT(t)
+	from.Set("name", "toko,pedia")
+	from.Set("binary", "\x00\xff\r\n")
+	from.RPush("queue", "a", "b,c")
+	from.ZAdd("rank", 1.5, "x")
+	from.HSet("shop", "name", "toko", "city", "jakarta")
+	from.SAdd("tags", "one", "two")
+	from.XAdd("events", "1-1", []string{"action", "buy"})
+
+	client := redis.NewClient(redis.Config{Address: from.Addr()})
+	defer client.Close()
+	buf := bytes.Buffer{}
+	sink, _ := redis.NewEncoder(format, &buf, true)
+	if err := client.DumpAll(context.Background(), redis.ScanOptions{Match: "*", Count: 10}, sink); err != nil {
+		t.Fatal(err)
+	}
+	sink.Flush()
+	source, _ := redis.NewDecoder(format, &buf, "")
+	toClient := redis.NewClient(redis.Config{Address: to.Addr()})
+	defer toClient.Close()
+	if _, err := toClient.Populate(context.Background(), source, redis.PopulateOptions{BatchSize: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, _ := to.Get("name"); v != "toko,pedia" {
+		t.Errorf("Expected name restored returned %v.\n", v)
+	}
+	if v, _ := to.Get("binary"); format == "binary" && v != "\x00\xff\r\n" {
+		t.Errorf("Expected binary restored returned %q.\n", v)
+	}
+	if v, _ := to.List("queue"); fmt.Sprint(v) != "[a b,c]" {
+		t.Errorf("Expected queue restored returned %v.\n", v)
+	}
+	if v, _ := to.ZScore("rank", "x"); v != 1.5 {
+		t.Errorf("Expected rank restored returned %v.\n", v)
+	}
+	if v := to.HGet("shop", "city"); v != "jakarta" {
+		t.Errorf("Expected shop restored returned %v.\n", v)
+	}
+	if v, _ := to.Members("tags"); fmt.Sprint(v) != "[one two]" {
+		t.Errorf("Expected tags restored returned %v.\n", v)
+	}
+	if v, _ := to.Stream("events"); len(v) != 1 || v[0].ID != "1-1" {
+		t.Errorf("Expected events restored returned %v.\n", v)
+	}
+}
+
+func TestPopulateInvalidBatchSize(t *testing.T) {
+	client := redis.NewClient(redis.Config{Address: "127.0.0.1:0"})
+	defer client.Close()
+	_, err := client.Populate(context.Background(), redis.NewCSVSource(strings.NewReader(""), "string"), redis.PopulateOptions{})
+	if err != redis.ErrInvalidBatchSize {
+		t.Fatalf("Expected ErrInvalidBatchSize returned %v.\n", err)
+	}
+}
@@ -1,47 +1,262 @@
 package redis
 
 import (
-	"fmt"
-	"log"
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/bits-and-blooms/bloom/v3"
 )
 
-// Scan scan redis key using matchPattern and return their value
-func Scan(address, password, matchPattern string, scanSize int64) {
-	// Initialize dependencies
-	redisClient := initRedis(address, password)
-
-	// Local variables for iterating the keys
-	var cursor uint64
-	visitedCursor := make(map[uint64]bool)
-	nScan := int64(1)
-	for {
-		// that's mean all cursor already visited
-		if visitedCursor[cursor] {
-			break
+// ErrClusterScan thrown if the keys of a cluster client are scanned, as SCAN only covers a single node.
+var ErrClusterScan = errors.New("cannot scan a redis cluster, scan each master node from ClusterMasters instead")
+
+// ScanOptions selects the keys to iterate.
+type ScanOptions struct {
+	// Match is the SCAN MATCH pattern, e.g. "product:*".
+	Match string
+	// Keys are the exact keys to iterate instead of scanning.
+	Keys []string
+	// KeyReader reads the exact keys to iterate instead of scanning, one key per line, e.g. a file or stdin.
+	// The keys are read batch by batch, so it may hold millions of keys.
+	KeyReader io.Reader
+	// Cursor is the SCAN cursor to start from, e.g. KeyIterator.Cursor of an interrupted scan.
+	// The scan ends once SCAN returns the cursor 0, the keys before Cursor are not returned.
+	Cursor uint64
+	// Progress is set to the cursor to resume from as the pages are processed, see KeyIterator.Cursor.
+	// It is written atomically, so it can be read while iterating, e.g. to report where an interrupted scan stopped.
+	Progress *uint64
+	// Dedup skips the keys returned more than once, as SCAN does when redis rehashes, using a Bloom filter
+	// sized for Dedup keys. The memory is bounded, about 2.4 bytes per key, but about 1 in 10,000 keys is
+	// a false positive once Dedup keys are seen, and is skipped although it was not returned before.
+	// It also bounds the memory used to skip the duplicate exact keys. 0 disables it for SCAN.
+	Dedup uint
+	// Count is the SCAN COUNT hint, also used as the page size to read the elements of a big value.
+	Count int64
+	// BatchSize is the maximum number of keys in a page, hence in a single pipeline. Default to 1000.
+	BatchSize int
+	// Workers is the number of pages of keys whose values are fetched concurrently by a dump, each on its own
+	// connection, while the keys are scanned. The entries are still written in the scan order, so the entries
+	// of a page, including the chunks of a big value, are held in memory until written. Default to 1.
+	Workers int
+	// OnError is called with the error of a single key, e.g. WRONGTYPE.
+	// Return nil to skip the key, or an error to stop. Default to log and skip the key.
+	OnError func(err error) error
+	// Limits throttles the commands sent while iterating and dumping the keys.
+	Limits Limits
+}
+
+// KeyIterator iterates the keys page by page, it is not safe for concurrent use.
+// The scan ends once SCAN returns the cursor 0, as a cursor may be returned again while redis rehashes.
+// The exact keys of Keys and KeyReader are iterated once each, the duplicates are skipped.
+//
+//	it := client.ScanKeys(ctx, opts)
+//	for it.Next() {
+//		keys := it.Keys()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type KeyIterator struct {
+	ctx    context.Context
+	client *Client
+	opts   ScanOptions
+
+	throttle   *throttle
+	cursor     uint64
+	pageCursor uint64
+	// done is set once SCAN returns the cursor 0
+	done bool
+	// exact returns the next exact key, io.EOF once there is no more, nil when scanning
+	exact func() (string, error)
+	// seen and filter hold the keys returned, filter is set with ScanOptions.Dedup
+	seen    map[string]struct{}
+	filter  *bloom.BloomFilter
+	pending []string
+	keys    []string
+	err     error
+}
+
+// dedupFalsePositiveRate is the false positive rate of the Bloom filter of ScanOptions.Dedup.
+const dedupFalsePositiveRate = 0.0001
+
+// defaultBatchSize is the number of keys in a page when ScanOptions.BatchSize is not set.
+const defaultBatchSize = 1000
+
+// ScanKeys returns an iterator of the keys selected by opts, using SCAN unless opts.Keys is given.
+func (c *Client) ScanKeys(ctx context.Context, opts ScanOptions) *KeyIterator {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	it := &KeyIterator{
+		ctx:      ctx,
+		client:   c,
+		opts:     opts,
+		throttle: newThrottle(c, opts.Limits),
+		cursor:   opts.Cursor,
+	}
+	if opts.Dedup > 0 {
+		it.filter = bloom.NewWithEstimates(opts.Dedup, dedupFalsePositiveRate)
+	}
+	if opts.Keys != nil || opts.KeyReader != nil {
+		it.exact = exactKeys(opts.Keys, opts.KeyReader)
+		if it.filter == nil {
+			it.seen = map[string]struct{}{}
+		}
+	}
+	return it
+}
+
+// exactKeys returns a function returning the keys one by one, then the lines of reader, then io.EOF.
+func exactKeys(keys []string, reader io.Reader) func() (string, error) {
+	var scanner *bufio.Scanner
+	if reader != nil {
+		scanner = bufio.NewScanner(reader)
+		scanner.Buffer(nil, 512*1024*1024)
+	}
+	return func() (string, error) {
+		if len(keys) > 0 {
+			key := keys[0]
+			keys = keys[1:]
+			return key, nil
 		}
-		// to handle starting cursor not 0
-		if visitedCursor[0] == false {
-			visitedCursor[0] = true
+		if scanner == nil {
+			return "", io.EOF
 		}
-		// get all keys from each scan
+		for scanner.Scan() {
+			if key := strings.TrimSuffix(scanner.Text(), "\r"); key != "" {
+				return key, nil
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+}
 
-		var keys []string
-		var nextCursor uint64
-		var err error
+// Next reads the next page of at most BatchSize keys, it returns false once there is no more keys or an error happened.
+func (it *KeyIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.err = it.ctx.Err(); it.err != nil {
+		return false
+	}
+	// the previous page is processed
+	if it.opts.Progress != nil {
+		atomic.StoreUint64(it.opts.Progress, it.Cursor())
+	}
 
-		keys, nextCursor, err = redisClient.Scan(cursor, matchPattern, scanSize).Result()
-		if err != nil {
-			log.Println("ERR", err)
+	if it.exact != nil {
+		it.keys, it.err = it.nextExact()
+	} else {
+		it.keys, it.err = it.nextScan()
+	}
+	if it.err != nil || len(it.keys) == 0 {
+		return false
+	}
+	if it.err = it.throttle.wait(it.ctx, 0, len(it.keys)); it.err != nil {
+		return false
+	}
+	return true
+}
+
+// nextExact reads the next batch of exact keys not seen yet.
+func (it *KeyIterator) nextExact() ([]string, error) {
+	keys := make([]string, 0, it.opts.BatchSize)
+	for len(keys) < it.opts.BatchSize {
+		key, err := it.exact()
+		if err == io.EOF {
 			break
 		}
+		if err != nil {
+			return nil, err
+		}
+		if it.returned(key) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
 
-		for _, key := range keys {
-			fmt.Println(key)
+// nextScan returns the next batch of the keys returned by SCAN, a SCAN page bigger than BatchSize is split.
+// It returns no keys once SCAN returns the cursor 0, SCAN may return empty pages before.
+func (it *KeyIterator) nextScan() ([]string, error) {
+	for len(it.pending) == 0 {
+		if it.done {
+			return nil, nil
+		}
+		if it.client.config.Cluster {
+			return nil, ErrClusterScan
+		}
+		if err := it.ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := it.wait(1); err != nil {
+			return nil, err
+		}
+		keys, nextCursor, err := it.client.redis.Scan(it.cursor, it.opts.Match, it.opts.Count).Result()
+		if err != nil {
+			return nil, err
+		}
+		it.pageCursor, it.cursor = it.cursor, nextCursor
+		it.done = nextCursor == 0
+		it.pending = keys
+		if it.filter != nil {
+			it.pending = it.pending[:0]
+			for _, key := range keys {
+				if !it.returned(key) {
+					it.pending = append(it.pending, key)
+				}
+			}
 		}
+	}
+	n := it.opts.BatchSize
+	if n > len(it.pending) {
+		n = len(it.pending)
+	}
+	keys := it.pending[:n:n]
+	it.pending = it.pending[n:]
+	return keys, nil
+}
 
-		// iterate for next
-		nScan++
-		visitedCursor[cursor] = true
-		cursor = nextCursor
+// returned reports whether the key was returned before, and records it.
+func (it *KeyIterator) returned(key string) bool {
+	if it.filter != nil {
+		return it.filter.TestAndAddString(key)
+	}
+	if _, ok := it.seen[key]; ok {
+		return true
 	}
+	it.seen[key] = struct{}{}
+	return false
+}
+
+// wait blocks until ops more commands are allowed by opts.Limits.
+func (it *KeyIterator) wait(ops int) error {
+	return it.throttle.wait(it.ctx, ops, 0)
+}
+
+// Cursor returns the SCAN cursor to resume from once the keys of the current and the previous pages are processed,
+// 0 once the scan is complete. A resumed scan may return some of these keys again.
+func (it *KeyIterator) Cursor() uint64 {
+	if len(it.pending) > 0 {
+		// the rest of the SCAN page is not returned yet
+		return it.pageCursor
+	}
+	return it.cursor
+}
+
+// Keys returns the current page of keys.
+func (it *KeyIterator) Keys() []string {
+	return it.keys
+}
+
+// Err returns the error stopping the iteration, if any.
+func (it *KeyIterator) Err() error {
+	return it.err
 }
@@ -0,0 +1,188 @@
+package redis_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+
+	"github.com/keenangebze/go/internal/pkg/redis"
+)
+
+func TestScanKeys(t *testing.T) {
+	s := miniredis.RunT(t)
+	for _, key := range []string{"user:1", "user:2", "user:3", "shop:1"} {
+		s.Set(key, "value")
+	}
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+
+	cases := []struct {
+		name     string
+		opts     redis.ScanOptions
+		expected []string
+	}{
+		{"match", redis.ScanOptions{Match: "user:*", Count: 1}, []string{"user:1", "user:2", "user:3"}},
+		{"exact keys", redis.ScanOptions{Keys: []string{"shop:1", "missing"}}, []string{"missing", "shop:1"}},
+		{"small batch", redis.ScanOptions{Match: "*", Count: 10, BatchSize: 1}, []string{"shop:1", "user:1", "user:2", "user:3"}},
+		{"key reader", redis.ScanOptions{Keys: []string{"user:1"}, KeyReader: strings.NewReader("user:2\r\n\nuser:1\nuser:2\n")}, []string{"user:1", "user:2"}},
+	}
+	for _, c := range cases {
+		var keys []string
+		it := client.ScanKeys(context.Background(), c.opts)
+		for it.Next() {
+			keys = append(keys, it.Keys()...)
+		}
+		if err := it.Err(); err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(keys)
+		if len(keys) != len(c.expected) {
+			t.Fatalf("%v: expected %v returned %v.\n", c.name, c.expected, keys)
+		}
+		for i := range keys {
+			if keys[i] != c.expected[i] {
+				t.Fatalf("%v: expected %v returned %v.\n", c.name, c.expected, keys)
+			}
+		}
+	}
+}
+
+// TestScanKeysBatches asserts the exact keys are read in bounded batches, each key once.
+func TestScanKeysBatches(t *testing.T) {
+	s := miniredis.RunT(t)
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+
+	lines := strings.Builder{}
+	for i := 0; i < 2500; i++ {
+		fmt.Fprintf(&lines, "key:%v\n", i%2000)
+	}
+	var pages []int
+	it := client.ScanKeys(context.Background(), redis.ScanOptions{KeyReader: strings.NewReader(lines.String()), BatchSize: 1000})
+	for it.Next() {
+		pages = append(pages, len(it.Keys()))
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(pages) != "[1000 1000]" {
+		t.Fatalf("Expected 2 pages of 1000 keys returned %v.\n", pages)
+	}
+}
+
+func TestScanKeysCursor(t *testing.T) {
+	// SCAN 0 returns a, b, c then SCAN 7 returns d
+	srv, err := server.NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	srv.Register("SCAN", func(c *server.Peer, cmd string, args []string) {
+		c.WriteLen(2)
+		if args[0] == "0" {
+			c.WriteBulk("7")
+			c.WriteStrings([]string{"a", "b", "c"})
+			return
+		}
+		c.WriteBulk("0")
+		c.WriteStrings([]string{"d"})
+	})
+	client := redis.NewClient(redis.Config{Address: srv.Addr().String()})
+	defer client.Close()
+
+	var pages []string
+	it := client.ScanKeys(context.Background(), redis.ScanOptions{Match: "*", BatchSize: 2})
+	for it.Next() {
+		pages = append(pages, fmt.Sprint(it.Keys(), it.Cursor()))
+	}
+	if fmt.Sprint(pages) != "[[a b] 0 [c] 7 [d] 0]" {
+		t.Fatalf("Expected the cursor to resume from after each page returned %v.\n", pages)
+	}
+
+	pages = nil
+	it = client.ScanKeys(context.Background(), redis.ScanOptions{Match: "*", Cursor: 7})
+	for it.Next() {
+		pages = append(pages, fmt.Sprint(it.Keys()))
+	}
+	if fmt.Sprint(pages) != "[[d]]" {
+		t.Fatalf("Expected to resume from cursor 7 returned %v.\n", pages)
+	}
+}
+
+// TestScanKeysRehash asserts the scan ends at the cursor 0 although a cursor is returned twice, as SCAN does while rehashing.
+func TestScanKeysRehash(t *testing.T) {
+	// SCAN 0 returns a, b then b, c, then an empty page, then SCAN 5 again returns d, a and ends
+	srv, err := server.NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	calls := map[string]int{}
+	srv.Register("SCAN", func(c *server.Peer, cmd string, args []string) {
+		calls[args[0]]++
+		c.WriteLen(2)
+		switch {
+		case args[0] == "0":
+			c.WriteBulk("5")
+			c.WriteStrings([]string{"a", "b"})
+		case args[0] == "5" && calls["5"] == 1:
+			c.WriteBulk("3")
+			c.WriteStrings([]string{"b", "c"})
+		case args[0] == "3":
+			c.WriteBulk("5")
+			c.WriteStrings(nil)
+		default:
+			c.WriteBulk("0")
+			c.WriteStrings([]string{"d", "a"})
+		}
+	})
+	client := redis.NewClient(redis.Config{Address: srv.Addr().String()})
+	defer client.Close()
+
+	cases := []struct {
+		name     string
+		opts     redis.ScanOptions
+		expected string
+	}{
+		{"duplicates", redis.ScanOptions{Match: "*"}, "[a b b c d a]"},
+		{"dedup", redis.ScanOptions{Match: "*", Dedup: 100}, "[a b c d]"},
+		{"resumed", redis.ScanOptions{Match: "*", Cursor: 3}, "[b c d a]"},
+	}
+	for _, c := range cases {
+		calls = map[string]int{}
+		var keys []string
+		var progress uint64
+		c.opts.Progress = &progress
+		it := client.ScanKeys(context.Background(), c.opts)
+		for it.Next() {
+			keys = append(keys, it.Keys()...)
+		}
+		if err := it.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(keys) != c.expected || progress != 0 {
+			t.Errorf("%v: expected %v returned %v, progress %v.\n", c.name, c.expected, keys, progress)
+		}
+	}
+}
+
+func TestScanKeysCancelled(t *testing.T) {
+	s := miniredis.RunT(t)
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	it := client.ScanKeys(ctx, redis.ScanOptions{Match: "*"})
+	if it.Next() {
+		t.Fatalf("Expected no page once cancelled.\n")
+	}
+	if it.Err() != context.Canceled {
+		t.Fatalf("Expected context.Canceled returned %v.\n", it.Err())
+	}
+}
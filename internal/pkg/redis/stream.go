@@ -0,0 +1,224 @@
+package redis
+
+import (
+	"context"
+	"io"
+	"log"
+
+	redis "github.com/go-redis/redis"
+
+	"github.com/keenangebze/go/pipeline"
+)
+
+// ReplayOptions configures Replay.
+type ReplayOptions struct {
+	// Stream is the stream the entries are added to. Default to the key of each entry.
+	Stream string
+	// KeepIDs adds the entries with their original IDs, otherwise redis generates new IDs.
+	// An ID not greater than the last ID of the stream is rejected by redis, and the entry is skipped.
+	KeepIDs bool
+	// MaxLen caps the length of the stream approximately (XADD MAXLEN ~), 0 means no limit.
+	MaxLen int64
+	// BatchSize is the number of entries added in a single pipeline. Default to 1000.
+	BatchSize int
+}
+
+// ReplayStats counts the stream entries replayed.
+type ReplayStats struct {
+	Added int64
+	// Skipped are the entries rejected by redis, invalid or not of a stream.
+	Skipped int64
+}
+
+// streamEntry is a stream entry queued by Replay.
+type streamEntry struct {
+	key, id string
+	cmd     *redis.Cmd
+}
+
+// Replay adds the stream entries of the source, e.g. written by DumpStreams, to a stream using XADD in pipelines
+// of ReplayOptions.BatchSize entries, in the order of the source.
+func (c *Client) Replay(ctx context.Context, source pipeline.Source[Entry], opts ReplayOptions) (ReplayStats, error) {
+	stats := ReplayStats{}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	pipe := c.redis.Pipeline()
+	defer pipe.Close()
+
+	batch := make([]streamEntry, 0, opts.BatchSize)
+	flush := func() error {
+		if _, err := pipe.Exec(); err != nil && !isRedisError(err) {
+			return err
+		}
+		for _, entry := range batch {
+			if err := entry.cmd.Err(); err != nil {
+				log.Println("[WARN] Rejected stream entry, skipping", entry.key, entry.id, err)
+				stats.Skipped++
+				continue
+			}
+			stats.Added++
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		entry, err := source.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stats, err
+		}
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		if entry.Type != "stream" {
+			log.Println("[WARN] Not a stream, skipping", entry.Key, entry.Type)
+			stats.Skipped++
+			continue
+		}
+		stream := opts.Stream
+		if stream == "" {
+			stream = entry.Key
+		}
+		for i := 0; i+1 < len(entry.Values); i += 2 {
+			fields, err := decodeStreamFields(entry.Values[i+1])
+			if err != nil || len(fields) == 0 || len(fields)%2 != 0 {
+				log.Println("[WARN] Invalid stream entry, skipping", entry.Key, entry.Values[i], err)
+				stats.Skipped++
+				continue
+			}
+			id := ""
+			if opts.KeepIDs {
+				id = entry.Values[i]
+			}
+			batch = append(batch, streamEntry{key: stream, id: entry.Values[i], cmd: pipe.Do(xaddArgs(stream, id, opts.MaxLen, fields)...)})
+			if len(batch) == opts.BatchSize {
+				if err := flush(); err != nil {
+					return stats, err
+				}
+			}
+		}
+	}
+	return stats, flush()
+}
+
+// StreamGroup is a consumer group of a stream (XINFO GROUPS) with its pending entries (XPENDING).
+type StreamGroup struct {
+	Stream          string `json:"stream"`
+	Name            string `json:"name"`
+	Consumers       int64  `json:"consumers"`
+	LastDeliveredID string `json:"last_delivered_id"`
+	// Lag is the number of entries not delivered to the group yet, -1 if unknown (before redis 7).
+	Lag int64 `json:"lag"`
+	// Pending is the number of entries delivered but not acknowledged, between the IDs Lowest and Highest.
+	Pending int64  `json:"pending"`
+	Lowest  string `json:"lowest,omitempty"`
+	Highest string `json:"highest,omitempty"`
+	// PendingByConsumer is the number of pending entries of each consumer.
+	PendingByConsumer map[string]int64 `json:"pending_by_consumer,omitempty"`
+}
+
+// StreamGroups returns the consumer groups of the stream keys selected by opts, the other keys are ignored.
+func (c *Client) StreamGroups(ctx context.Context, opts ScanOptions) ([]StreamGroup, error) {
+	var groups []StreamGroup
+	pipe := c.redis.Pipeline()
+	defer pipe.Close()
+
+	it := c.ScanKeys(ctx, opts)
+	for it.Next() {
+		keys := it.Keys()
+		types := make([]*redis.StatusCmd, len(keys))
+		for i, key := range keys {
+			types[i] = pipe.Type(key)
+		}
+		if err := it.wait(len(keys)); err != nil {
+			return groups, err
+		}
+		if _, err := pipe.Exec(); err != nil && !isRedisError(err) {
+			return groups, err
+		}
+
+		infos := map[string]*redis.Cmd{}
+		for i, key := range keys {
+			if types[i].Val() == "stream" {
+				infos[key] = pipe.Do("XINFO", "GROUPS", key)
+			}
+		}
+		if err := it.wait(len(infos)); err != nil {
+			return groups, err
+		}
+		if _, err := pipe.Exec(); err != nil && !isRedisError(err) {
+			return groups, err
+		}
+
+		var page []StreamGroup
+		for _, key := range keys {
+			if infos[key] == nil {
+				continue
+			}
+			reply, err := infos[key].Result()
+			if err != nil {
+				if err := keyError(opts.OnError, key, err); err != nil {
+					return groups, err
+				}
+				continue
+			}
+			page = append(page, parseStreamGroups(key, reply)...)
+		}
+		pending := make([]*redis.XPendingCmd, len(page))
+		for i, group := range page {
+			pending[i] = pipe.XPending(group.Stream, group.Name)
+		}
+		if err := it.wait(len(page)); err != nil {
+			return groups, err
+		}
+		if _, err := pipe.Exec(); err != nil && !isRedisError(err) {
+			return groups, err
+		}
+		for i := range page {
+			if summary, err := pending[i].Result(); err == nil {
+				page[i].Pending = summary.Count
+				page[i].Lowest, page[i].Highest = summary.Lower, summary.Higher
+				page[i].PendingByConsumer = summary.Consumers
+			}
+		}
+		groups = append(groups, page...)
+	}
+	return groups, it.Err()
+}
+
+// parseStreamGroups reads the reply of XINFO GROUPS, a list of field and value lists.
+func parseStreamGroups(stream string, reply interface{}) []StreamGroup {
+	list, _ := reply.([]interface{})
+	groups := make([]StreamGroup, 0, len(list))
+	for _, item := range list {
+		fields, _ := item.([]interface{})
+		group := StreamGroup{Stream: stream, Lag: -1}
+		for i := 0; i+1 < len(fields); i += 2 {
+			name, _ := fields[i].(string)
+			switch value := fields[i+1].(type) {
+			case string:
+				switch name {
+				case "name":
+					group.Name = value
+				case "last-delivered-id":
+					group.LastDeliveredID = value
+				}
+			case int64:
+				switch name {
+				case "consumers":
+					group.Consumers = value
+				case "pending":
+					group.Pending = value
+				case "lag":
+					group.Lag = value
+				}
+			}
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
@@ -0,0 +1,136 @@
+package redis_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/go-redis/redis"
+
+	"github.com/keenangebze/go/internal/pkg/redis"
+	"github.com/keenangebze/go/pipeline"
+)
+
+func TestStreams(t *testing.T) {
+	s := miniredis.RunT(t)
+	for _, id := range []string{"1-1", "1-2", "2-0", "3-5", "4-0"} {
+		if _, err := s.XAdd("orders", id, []string{"id", id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Set("other", "a")
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+
+	dump := bytes.Buffer{}
+	sink := redis.NewJSONLSink(&dump)
+	chunks := 0
+	err := client.DumpStreams(ctx, redis.ScanOptions{Keys: []string{"orders"}, Count: 2}, pipeline.SinkFunc[redis.Entry](func(ctx context.Context, entry redis.Entry) error {
+		chunks++
+		return sink.Write(ctx, entry)
+	}))
+	sink.Flush()
+	if err != nil || chunks != 3 {
+		t.Fatalf("Expected 3 XRANGE pages returned %v %v.\n", chunks, err)
+	}
+	// a full last page is not followed by an empty chunk
+	chunks = 0
+	err = client.DumpStreams(ctx, redis.ScanOptions{Keys: []string{"orders"}, Count: 5}, pipeline.SinkFunc[redis.Entry](func(ctx context.Context, entry redis.Entry) error {
+		chunks++
+		return nil
+	}))
+	if err != nil || chunks != 1 {
+		t.Fatalf("Expected a single chunk returned %v %v.\n", chunks, err)
+	}
+
+	// replay twice with the original IDs, the second time is rejected
+	stats, err := client.Replay(ctx, redis.NewJSONLSource(bytes.NewReader(dump.Bytes())), redis.ReplayOptions{Stream: "copy", KeepIDs: true, BatchSize: 2})
+	if err != nil || stats != (redis.ReplayStats{Added: 5}) {
+		t.Fatalf("Expected 5 entries added returned %+v %v.\n", stats, err)
+	}
+	stats, err = client.Replay(ctx, redis.NewJSONLSource(bytes.NewReader(dump.Bytes())), redis.ReplayOptions{Stream: "copy", KeepIDs: true})
+	if err != nil || stats != (redis.ReplayStats{Skipped: 5}) {
+		t.Fatalf("Expected 5 entries skipped returned %+v %v.\n", stats, err)
+	}
+	stream, _ := s.Stream("copy")
+	if len(stream) != 5 || stream[3].ID != "3-5" || !reflect.DeepEqual(stream[3].Values, []string{"id", "3-5"}) {
+		t.Errorf("Expected the entries copied with their IDs returned %v.\n", stream)
+	}
+	stats, err = client.Replay(ctx, redis.NewJSONLSource(bytes.NewReader(dump.Bytes())), redis.ReplayOptions{})
+	if err != nil || stats.Added != 5 {
+		t.Fatalf("Expected 5 entries added with new IDs returned %+v %v.\n", stats, err)
+	}
+	if stream, _ := s.Stream("orders"); len(stream) != 10 || stream[5].ID == "1-1" {
+		t.Errorf("Expected the entries added with new IDs returned %v.\n", stream)
+	}
+}
+
+// TestStreamFields asserts the fields of an entry are replayed in order, with their duplicates and binary values.
+func TestStreamFields(t *testing.T) {
+	s := miniredis.RunT(t)
+	fields := []string{"b", "1", "a", "\xff\x00", "b", "2"}
+	if _, err := s.XAdd("orders", "1-1", fields); err != nil {
+		t.Fatal(err)
+	}
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+
+	for _, format := range []string{"jsonl", "binary"} {
+		dump := bytes.Buffer{}
+		sink, _ := redis.NewEncoder(format, &dump, false)
+		if err := client.DumpStreams(ctx, redis.ScanOptions{Keys: []string{"orders"}}, sink); err != nil {
+			t.Fatal(err)
+		}
+		sink.Flush()
+		source, _ := redis.NewDecoder(format, &dump, "stream")
+		if _, err := client.Replay(ctx, source, redis.ReplayOptions{Stream: format, KeepIDs: true}); err != nil {
+			t.Fatal(err)
+		}
+		if stream, _ := s.Stream(format); len(stream) != 1 || !reflect.DeepEqual(stream[0].Values, fields) {
+			t.Errorf("%v: expected the fields %q replayed returned %q.\n", format, fields, stream)
+		}
+	}
+	// the fields object of the older dumps
+	legacy := `{"type":"stream","key":"orders","value":[{"id":"1-1","values":{"action":"buy"}}]}`
+	if _, err := client.Replay(ctx, redis.NewJSONLSource(strings.NewReader(legacy)), redis.ReplayOptions{Stream: "legacy"}); err != nil {
+		t.Fatal(err)
+	}
+	if stream, _ := s.Stream("legacy"); len(stream) != 1 || !reflect.DeepEqual(stream[0].Values, []string{"action", "buy"}) {
+		t.Errorf("Expected the legacy fields replayed returned %q.\n", stream)
+	}
+}
+
+func TestStreamGroups(t *testing.T) {
+	s := miniredis.RunT(t)
+	s.XAdd("orders", "1-1", []string{"id", "1"})
+	s.XAdd("orders", "1-2", []string{"id", "2"})
+	s.Set("other", "a")
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+	// miniredis has no consumer group helpers, consume with a plain client
+	consumer := goredis.NewClient(&goredis.Options{Addr: s.Addr()})
+	defer consumer.Close()
+	if err := consumer.XGroupCreate("orders", "billing", "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := consumer.XReadGroup(&goredis.XReadGroupArgs{Group: "billing", Consumer: "worker-1", Streams: []string{"orders", ">"}, Count: 1}).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := client.StreamGroups(context.Background(), redis.ScanOptions{Match: "*", Count: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []redis.StreamGroup{{
+		Stream: "orders", Name: "billing", Consumers: 1, LastDeliveredID: "1-1", Lag: 2,
+		Pending: 1, Lowest: "1-1", Highest: "1-1", PendingByConsumer: map[string]int64{"worker-1": 1},
+	}}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("Expected groups %+v returned %+v.\n", expected, groups)
+	}
+}
@@ -0,0 +1,163 @@
+package redis
+
+import (
+	"context"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	redis "github.com/go-redis/redis"
+
+	"github.com/keenangebze/go/pipeline"
+)
+
+// TailOptions configures Tail.
+type TailOptions struct {
+	// Channels are the channels subscribed, a channel with a glob pattern (*, ? or [) is subscribed with PSUBSCRIBE.
+	Channels []string
+	// KeyspaceEvents are the keyspace notifications tailed, e.g. set, del or expired, or "*" for every event.
+	// The notifications must be enabled in the notify-keyspace-events config of the redis, e.g. KEA.
+	KeyspaceEvents []string
+	// Match is the pattern of the keys of the keyspace notifications. Default to "*".
+	Match string
+	// FetchValues reads the value of the key of each keyspace event once notified, as DumpAll does.
+	FetchValues bool
+}
+
+// Event is a message received by Tail.
+type Event struct {
+	Time    time.Time `json:"time"`
+	Channel string    `json:"channel"`
+	// Payload is the message published, empty for a keyspace event.
+	Payload string `json:"payload,omitempty"`
+	// Event is the keyspace event, e.g. set, del or expired, of the Key.
+	Event string `json:"event,omitempty"`
+	Key   string `json:"key,omitempty"`
+	// Type and Values are the value of the Key once notified, see TailOptions.FetchValues.
+	// The Type of a deleted or expired key is none.
+	Type   string   `json:"type,omitempty"`
+	Values []string `json:"values,omitempty"`
+}
+
+// keyspacePrefix is the prefix of the channels of the keyspace notifications, followed by the database and the key,
+// e.g. __keyspace@0__:product:1.
+const keyspacePrefix = "__keyspace@"
+
+// receiveTimeout is the time waiting for a message before checking the context.
+const receiveTimeout = time.Second
+
+// Tail subscribes to the channels and the keyspace notifications of opts, and writes the events received to the sink
+// until ctx is done. The keyspace notifications are only received from this node, each node of a cluster must be tailed.
+//
+// The subscriptions are restored once the connection is lost, with an exponential backoff up to 5s.
+// The messages published while disconnected are lost.
+func (c *Client) Tail(ctx context.Context, opts TailOptions, sink pipeline.Sink[Event]) error {
+	if opts.Match == "" {
+		opts.Match = "*"
+	}
+	events := map[string]bool{}
+	for _, event := range opts.KeyspaceEvents {
+		events[event] = true
+	}
+	var channels, patterns []string
+	for _, channel := range opts.Channels {
+		if strings.ContainsAny(channel, "*?[") {
+			patterns = append(patterns, channel)
+		} else {
+			channels = append(channels, channel)
+		}
+	}
+	if len(events) > 0 {
+		patterns = append(patterns, keyspacePrefix+"*__:"+opts.Match)
+		c.checkNotifications()
+	}
+
+	pubsub := c.redis.Subscribe()
+	defer pubsub.Close()
+	if len(channels) > 0 {
+		if err := pubsub.Subscribe(channels...); err != nil {
+			return err
+		}
+	}
+	if len(patterns) > 0 {
+		if err := pubsub.PSubscribe(patterns...); err != nil {
+			return err
+		}
+	}
+
+	pipe := c.redis.Pipeline()
+	defer pipe.Close()
+	var backoff time.Duration
+	for ctx.Err() == nil {
+		received, err := pubsub.ReceiveTimeout(receiveTimeout)
+		if err, ok := err.(net.Error); ok && err.Timeout() {
+			continue
+		}
+		if err != nil {
+			// the next receive reconnects and subscribes again
+			backoff *= 2
+			if backoff == 0 {
+				backoff = 100 * time.Millisecond
+			}
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+			log.Println("[WARN] Lost the subscription, reconnecting in", backoff, err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(backoff):
+			}
+			continue
+		}
+		backoff = 0
+
+		message, ok := received.(*redis.Message)
+		if !ok {
+			// subscription confirmations and pongs
+			continue
+		}
+		event := Event{Time: time.Now(), Channel: message.Channel, Payload: message.Payload}
+		if strings.HasPrefix(message.Channel, keyspacePrefix) && message.Pattern == keyspacePrefix+"*__:"+opts.Match {
+			if !events["*"] && !events[message.Payload] {
+				continue
+			}
+			event.Event, event.Payload = message.Payload, ""
+			event.Key = message.Channel[strings.Index(message.Channel, "__:")+3:]
+			if opts.FetchValues {
+				if err := c.fetchEvent(pipe, &event); err != nil {
+					return err
+				}
+			}
+		}
+		if err := sink.Write(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchEvent reads the value of the key of the event.
+func (c *Client) fetchEvent(pipe redis.Pipeliner, event *Event) error {
+	types, values, errs, err := typedValues(func(int) error { return nil }, pipe, []string{event.Key})
+	if err != nil {
+		return err
+	}
+	event.Type, event.Values = types[0], values[0]
+	if errs[0] != nil {
+		// the event is still written without the value
+		return keyError(nil, event.Key, errs[0])
+	}
+	return nil
+}
+
+// checkNotifications logs a warning if the keyspace notifications are disabled, it is skipped if CONFIG is not allowed.
+func (c *Client) checkNotifications() {
+	config, err := c.redis.ConfigGet("notify-keyspace-events").Result()
+	if err != nil || len(config) < 2 {
+		return
+	}
+	if flags, _ := config[1].(string); !strings.Contains(flags, "K") {
+		log.Println(`[WARN] Keyspace notifications are disabled, enable them with CONFIG SET notify-keyspace-events KEA`)
+	}
+}
@@ -0,0 +1,87 @@
+package redis_test
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/keenangebze/go/internal/pkg/redis"
+)
+
+// events collects the events written to the sink.
+type events struct {
+	mu     sync.Mutex
+	events []redis.Event
+}
+
+func (e *events) Write(ctx context.Context, event redis.Event) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.events = append(e.events, event)
+	return nil
+}
+
+func (e *events) len() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.events)
+}
+
+func TestTail(t *testing.T) {
+	s := miniredis.RunT(t)
+	s.Set("product:1", "toko")
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got := &events{}
+	done := make(chan error)
+	go func() {
+		done <- client.Tail(ctx, redis.TailOptions{
+			Channels:       []string{"invalidate", "cache:*"},
+			KeyspaceEvents: []string{"set", "del"},
+			Match:          "product:*",
+			FetchValues:    true,
+		}, got)
+	}()
+	for s.PubSubNumPat() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// miniredis has no keyspace notifications, publish them as redis would
+	s.Publish("__keyspace@0__:product:1", "expire")
+	s.Publish("__keyspace@0__:product:1", "set")
+	s.Publish("__keyspace@0__:user:1", "set")
+	s.Publish("invalidate", "product:1")
+	s.Publish("cache:product", "product:2")
+	for got.len() != 3 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// the subscriptions are not ordered with each other
+	sort.Slice(got.events, func(i, j int) bool { return got.events[i].Channel < got.events[j].Channel })
+	expected := []redis.Event{
+		{Channel: "__keyspace@0__:product:1", Event: "set", Key: "product:1", Type: "string", Values: []string{"toko"}},
+		{Channel: "cache:product", Payload: "product:2"},
+		{Channel: "invalidate", Payload: "product:1"},
+	}
+	for i, event := range got.events {
+		if event.Time.IsZero() {
+			t.Errorf("Expected the time of event %v.\n", i)
+		}
+		event.Time = time.Time{}
+		if event.Channel != expected[i].Channel || event.Payload != expected[i].Payload || event.Event != expected[i].Event ||
+			event.Key != expected[i].Key || event.Type != expected[i].Type || len(event.Values) != len(expected[i].Values) {
+			t.Errorf("Expected event %+v returned %+v.\n", expected[i], event)
+		}
+	}
+}
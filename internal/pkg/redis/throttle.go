@@ -0,0 +1,161 @@
+package redis
+
+import (
+	"context"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Limits bounds the load put on a redis node by a scan or a dump, each node dumped concurrently is limited on its own.
+type Limits struct {
+	// OpsPerSecond is the maximum number of commands sent per second, a pipeline counts one command per key.
+	// 0 means unlimited.
+	OpsPerSecond float64
+	// KeysPerSecond is the maximum number of keys read per second, 0 means unlimited.
+	KeysPerSecond float64
+	// MaxServerOps backs off while the instantaneous_ops_per_sec of the server exceeds it, 0 disables it.
+	MaxServerOps int64
+	// MaxLatency backs off while the round trip of INFO exceeds it, 0 disables it.
+	MaxLatency time.Duration
+	// SampleInterval is how often INFO is sampled for MaxServerOps and MaxLatency, default to 1s.
+	SampleInterval time.Duration
+}
+
+// maxBackoff is the longest pause between two INFO samples while the server is busy.
+const maxBackoff = 5 * time.Second
+
+// throttle enforces the Limits of a single iteration, it is safe for concurrent use.
+type throttle struct {
+	client *Client
+	limits Limits
+	ops    *rate.Limiter
+	keys   *rate.Limiter
+
+	// mu guards the INFO sampling, so a single goroutine samples while the others wait
+	mu      sync.Mutex
+	sampled time.Time
+	backoff time.Duration
+}
+
+func newThrottle(client *Client, limits Limits) *throttle {
+	if limits.SampleInterval <= 0 {
+		limits.SampleInterval = time.Second
+	}
+	return &throttle{
+		client: client,
+		limits: limits,
+		ops:    newLimiter(limits.OpsPerSecond),
+		keys:   newLimiter(limits.KeysPerSecond),
+	}
+}
+
+// newLimiter returns a token bucket allowing perSecond events with a burst of 100ms worth of events,
+// or nil if perSecond is not positive.
+func newLimiter(perSecond float64) *rate.Limiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(perSecond), int(math.Max(1, math.Ceil(perSecond/10))))
+}
+
+// wait blocks until ops more commands reading keys more keys are allowed, and while the server is busy.
+func (t *throttle) wait(ctx context.Context, ops, keys int) error {
+	if err := waitN(ctx, t.ops, ops); err != nil {
+		return err
+	}
+	if err := waitN(ctx, t.keys, keys); err != nil {
+		return err
+	}
+	return t.waitServer(ctx)
+}
+
+// waitN waits for n tokens of the limiter, burst by burst as WaitN fails above the burst.
+func waitN(ctx context.Context, limiter *rate.Limiter, n int) error {
+	if limiter == nil {
+		return nil
+	}
+	for n > 0 {
+		chunk := n
+		if chunk > limiter.Burst() {
+			chunk = limiter.Burst()
+		}
+		if err := limiter.WaitN(ctx, chunk); err != nil {
+			return err
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+// waitServer samples INFO every SampleInterval, and backs off exponentially while the server is busy.
+func (t *throttle) waitServer(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.limits.MaxServerOps <= 0 && t.limits.MaxLatency <= 0 {
+		return nil
+	}
+	for time.Since(t.sampled) >= t.limits.SampleInterval {
+		busy, err := t.busy()
+		if err != nil {
+			if !isRedisError(err) {
+				return err
+			}
+			// e.g. INFO is disabled, keep going with the static limits only
+			log.Println("[WARN] Cannot sample INFO, adaptive throttling disabled", err)
+			t.limits.MaxServerOps, t.limits.MaxLatency = 0, 0
+			return nil
+		}
+		t.sampled = time.Now()
+		if !busy {
+			t.backoff = 0
+			return nil
+		}
+
+		if t.backoff == 0 {
+			t.backoff = 100 * time.Millisecond
+		} else if t.backoff *= 2; t.backoff > maxBackoff {
+			t.backoff = maxBackoff
+		}
+		timer := time.NewTimer(t.backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		// sample again right away
+		t.sampled = time.Time{}
+	}
+	return nil
+}
+
+// busy reports whether the server exceeds MaxServerOps or MaxLatency according to INFO stats.
+func (t *throttle) busy() (bool, error) {
+	start := time.Now()
+	info, err := t.client.redis.Info("stats").Result()
+	if err != nil {
+		return false, err
+	}
+	if t.limits.MaxLatency > 0 && time.Since(start) > t.limits.MaxLatency {
+		return true, nil
+	}
+	return t.limits.MaxServerOps > 0 && infoInt(info, "instantaneous_ops_per_sec") > t.limits.MaxServerOps, nil
+}
+
+// infoInt returns the integer field of an INFO reply, 0 if missing.
+func infoInt(info, field string) int64 {
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, field+":") {
+			n, _ := strconv.ParseInt(strings.TrimPrefix(line, field+":"), 10, 64)
+			return n
+		}
+	}
+	return 0
+}
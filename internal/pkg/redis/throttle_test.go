@@ -0,0 +1,94 @@
+package redis_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+
+	"github.com/keenangebze/go/internal/pkg/redis"
+)
+
+func TestLimitsKeysPerSecond(t *testing.T) {
+	s := miniredis.RunT(t)
+	for i := 0; i < 30; i++ {
+		s.Set(fmt.Sprint("key:", i), "value")
+	}
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+
+	// the first 10 keys are the burst, the next 20 keys take 200ms
+	start := time.Now()
+	keys := 0
+	it := client.ScanKeys(context.Background(), redis.ScanOptions{Match: "*", Count: 10, Limits: redis.Limits{KeysPerSecond: 100}})
+	for it.Next() {
+		keys += len(it.Keys())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if keys != 30 {
+		t.Fatalf("Expected 30 keys returned %v.\n", keys)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("Expected the scan throttled returned %v.\n", elapsed)
+	}
+}
+
+func TestLimitsServerBusy(t *testing.T) {
+	srv, err := server.NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	var infos int32
+	srv.Register("INFO", func(c *server.Peer, cmd string, args []string) {
+		// busy for the first two samples
+		ops := 0
+		if atomic.AddInt32(&infos, 1) <= 2 {
+			ops = 500
+		}
+		c.WriteBulk(fmt.Sprintf("# Stats\r\ninstantaneous_ops_per_sec:%v\r\n", ops))
+	})
+	srv.Register("SCAN", func(c *server.Peer, cmd string, args []string) {
+		c.WriteLen(2)
+		c.WriteBulk("0")
+		c.WriteStrings([]string{"key"})
+	})
+	client := redis.NewClient(redis.Config{Address: srv.Addr().String()})
+	defer client.Close()
+
+	// backs off 100ms then 200ms
+	start := time.Now()
+	it := client.ScanKeys(context.Background(), redis.ScanOptions{Match: "*", Limits: redis.Limits{MaxServerOps: 100}})
+	for it.Next() {
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&infos); n != 3 {
+		t.Fatalf("Expected 3 INFO samples returned %v.\n", n)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Fatalf("Expected the scan to back off returned %v.\n", elapsed)
+	}
+}
+
+func TestLimitsInfoUnsupported(t *testing.T) {
+	// miniredis does not support INFO stats, the scan goes on without adaptive throttling
+	s := miniredis.RunT(t)
+	s.Set("key", "value")
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+
+	it := client.ScanKeys(context.Background(), redis.ScanOptions{Match: "*", Limits: redis.Limits{MaxServerOps: 100}})
+	for it.Next() {
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,147 @@
+package redis
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	redis "github.com/go-redis/redis"
+)
+
+// ExpireOptions configures Expire.
+type ExpireOptions struct {
+	// TTL is the time to live set on each key.
+	TTL time.Duration
+	// Jitter adds a random duration up to Jitter to the TTL of each key, so the keys do not all expire at once.
+	Jitter time.Duration
+	// OnlyPersistent only sets the TTL of the keys without expiry, keeping the existing TTLs.
+	OnlyPersistent bool
+}
+
+// TTLHistogram returns the number of keys selected by opts in each of TTLBuckets, and "none" for no expiry.
+// The TTL of each page of keys is pipelined.
+func (c *Client) TTLHistogram(ctx context.Context, opts ScanOptions) (map[string]int64, error) {
+	histogram := map[string]int64{}
+	err := c.forEachPage(ctx, opts, func(it *KeyIterator, pipe redis.Pipeliner, keys []string) error {
+		ttls, err := c.ttls(it, pipe, keys)
+		if err != nil {
+			return err
+		}
+		for _, ttl := range ttls {
+			if ttl == -2 {
+				// deleted since scanned
+				continue
+			}
+			histogram[ttlBucket(ttl)]++
+		}
+		return nil
+	})
+	return histogram, err
+}
+
+// Expire sets the TTL of the keys selected by opts using PEXPIRE, it returns the number of keys updated.
+func (c *Client) Expire(ctx context.Context, opts ScanOptions, expire ExpireOptions) (int64, error) {
+	var updated int64
+	// the global source is not seeded before go 1.20, every run would spread the TTLs alike
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	err := c.forEachPage(ctx, opts, func(it *KeyIterator, pipe redis.Pipeliner, keys []string) error {
+		if expire.OnlyPersistent {
+			ttls, err := c.ttls(it, pipe, keys)
+			if err != nil {
+				return err
+			}
+			persistent := keys[:0:0]
+			for i, key := range keys {
+				if ttls[i] == -1 {
+					persistent = append(persistent, key)
+				}
+			}
+			keys = persistent
+		}
+
+		cmds := make([]*redis.BoolCmd, len(keys))
+		for i, key := range keys {
+			ttl := expire.TTL
+			if expire.Jitter > 0 {
+				ttl += time.Duration(random.Int63n(int64(expire.Jitter)))
+			}
+			cmds[i] = pipe.PExpire(key, ttl)
+		}
+		n, err := c.execCount(it, pipe, keys, func(i int) (bool, error) { return cmds[i].Result() })
+		updated += n
+		return err
+	})
+	return updated, err
+}
+
+// Persist removes the TTL of the keys selected by opts using PERSIST, it returns the number of keys updated.
+func (c *Client) Persist(ctx context.Context, opts ScanOptions) (int64, error) {
+	var updated int64
+	err := c.forEachPage(ctx, opts, func(it *KeyIterator, pipe redis.Pipeliner, keys []string) error {
+		cmds := make([]*redis.BoolCmd, len(keys))
+		for i, key := range keys {
+			cmds[i] = pipe.Persist(key)
+		}
+		n, err := c.execCount(it, pipe, keys, func(i int) (bool, error) { return cmds[i].Result() })
+		updated += n
+		return err
+	})
+	return updated, err
+}
+
+// forEachPage calls fn with each page of keys selected by opts and a pipeline to queue the commands of the page.
+func (c *Client) forEachPage(ctx context.Context, opts ScanOptions, fn func(it *KeyIterator, pipe redis.Pipeliner, keys []string) error) error {
+	pipe := c.redis.Pipeline()
+	defer pipe.Close()
+
+	it := c.ScanKeys(ctx, opts)
+	for it.Next() {
+		if err := fn(it, pipe, it.Keys()); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// ttls pipelines the TTL of the keys in seconds, -1 for no expiry and -2 for a missing key.
+func (c *Client) ttls(it *KeyIterator, pipe redis.Pipeliner, keys []string) ([]int64, error) {
+	cmds := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.TTL(key)
+	}
+	if err := it.wait(len(keys)); err != nil {
+		return nil, err
+	}
+	if _, err := pipe.Exec(); err != nil && !isRedisError(err) {
+		return nil, err
+	}
+	ttls := make([]int64, len(keys))
+	for i, cmd := range cmds {
+		ttls[i] = int64(cmd.Val() / time.Second)
+	}
+	return ttls, nil
+}
+
+// execCount executes the commands queued for the keys, and returns the number of them whose result is true.
+func (c *Client) execCount(it *KeyIterator, pipe redis.Pipeliner, keys []string, result func(i int) (bool, error)) (int64, error) {
+	if err := it.wait(len(keys)); err != nil {
+		return 0, err
+	}
+	if _, err := pipe.Exec(); err != nil && !isRedisError(err) {
+		return 0, err
+	}
+	var n int64
+	for i, key := range keys {
+		ok, err := result(i)
+		if err != nil {
+			if err := keyError(it.opts.OnError, key, err); err != nil {
+				return n, err
+			}
+			continue
+		}
+		if ok {
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,53 @@
+package redis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/keenangebze/go/internal/pkg/redis"
+)
+
+func TestTTL(t *testing.T) {
+	s := miniredis.RunT(t)
+	s.Set("session:1", "a")
+	s.Set("session:2", "b")
+	s.Set("session:3", "c")
+	s.SetTTL("session:3", 2*time.Hour)
+	s.Set("user:1", "d")
+	client := redis.NewClient(redis.Config{Address: s.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+	opts := redis.ScanOptions{Match: "session:*", Count: 10, BatchSize: 2}
+
+	histogram, err := client.TTLHistogram(ctx, opts)
+	if err != nil || histogram["none"] != 2 || histogram["<1d"] != 1 || len(histogram) != 2 {
+		t.Fatalf("Expected 2 keys without expiry and 1 below a day returned %v %v.\n", histogram, err)
+	}
+
+	n, err := client.Expire(ctx, opts, redis.ExpireOptions{TTL: time.Hour, Jitter: time.Minute, OnlyPersistent: true})
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 keys updated returned %v %v.\n", n, err)
+	}
+	for _, key := range []string{"session:1", "session:2"} {
+		if ttl := s.TTL(key); ttl < time.Hour || ttl >= time.Hour+time.Minute {
+			t.Errorf("Expected %v TTL within the jitter returned %v.\n", key, ttl)
+		}
+	}
+	if ttl := s.TTL("session:3"); ttl != 2*time.Hour {
+		t.Errorf("Expected the existing TTL kept returned %v.\n", ttl)
+	}
+	if ttl := s.TTL("user:1"); ttl != 0 {
+		t.Errorf("Expected the unmatched key without TTL returned %v.\n", ttl)
+	}
+
+	n, err = client.Persist(ctx, redis.ScanOptions{Keys: []string{"session:1", "session:3", "user:1", "missing"}})
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 keys persisted returned %v %v.\n", n, err)
+	}
+	if s.TTL("session:1") != 0 || s.TTL("session:3") != 0 || s.TTL("session:2") == 0 {
+		t.Errorf("Expected the TTL of the exact keys removed.\n")
+	}
+}
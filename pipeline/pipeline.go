@@ -0,0 +1,310 @@
+/*
+Package pipeline runs records from a Source through a Stage in parallel and writes them to a Sink.
+
+It is the shared reader → workers → writer machinery behind the csv, jsonl2csv and csv2jsonl packages,
+so every format has the same concurrency, ordering, error and cancellation semantics:
+
+  - The Source is read by a single goroutine and the Sink is written by a single goroutine,
+    only the Stage runs in parallel.
+  - The records reach the Sink in any order, or in the Source order if Options.Ordered is set.
+  - A Stage or a Sink may return ErrSkip to drop a record. Their other errors abort the pipeline unless
+    Options.OnError decides to skip them, e.g. a row the Sink rejects.
+  - Once aborted or the context is cancelled, the Source stops being read, the in-flight records
+    are discarded and Run returns the first error.
+  - A Source error stops the Source being read, but the records already read are still written,
+    e.g. the valid rows before a truncated input. Run then returns the Source error.
+*/
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// ErrSkip is returned by a Stage or a Sink to drop the record without aborting the pipeline.
+var ErrSkip = errors.New("skip record")
+
+// ErrInvalidWorkers thrown if Options.Workers < 0
+var ErrInvalidWorkers = errors.New("invalid number of workers, must not be negative")
+
+// Source produces the records. Next returns io.EOF once there is no more record.
+type Source[T any] interface {
+	Next(ctx context.Context) (T, error)
+}
+
+// Stage transforms a record. It is called concurrently from Options.Workers goroutines.
+type Stage[T, U any] interface {
+	Process(ctx context.Context, in T) (U, error)
+}
+
+// Sink consumes the transformed records.
+type Sink[U any] interface {
+	Write(ctx context.Context, out U) error
+}
+
+// SourceFunc adapts a function in to a Source.
+type SourceFunc[T any] func(ctx context.Context) (T, error)
+
+// Next implements Source.
+func (f SourceFunc[T]) Next(ctx context.Context) (T, error) {
+	return f(ctx)
+}
+
+// StageFunc adapts a function in to a Stage.
+type StageFunc[T, U any] func(ctx context.Context, in T) (U, error)
+
+// Process implements Stage.
+func (f StageFunc[T, U]) Process(ctx context.Context, in T) (U, error) {
+	return f(ctx, in)
+}
+
+// SinkFunc adapts a function in to a Sink.
+type SinkFunc[U any] func(ctx context.Context, out U) error
+
+// Write implements Sink.
+func (f SinkFunc[U]) Write(ctx context.Context, out U) error {
+	return f(ctx, out)
+}
+
+// Options configures how Run processes the records. Zero values use the defaults.
+type Options struct {
+	// Workers is the number of goroutine running the Stage, default to runtime.NumCPU().
+	Workers int
+	// Ordered keeps the Source ordering in the Sink.
+	Ordered bool
+	// Window is the maximum number of records read from the Source but not yet written to the Sink,
+	// default to twice the Workers. It bounds the memory used to reorder the records.
+	Window int
+	// OnError is called with the Stage or Sink error other than ErrSkip.
+	// Return nil to skip the record, or an error to abort the pipeline. Default to abort.
+	OnError func(err error) error
+}
+
+// SkipErrors is an Options.OnError that skips every failed record.
+func SkipErrors(err error) error {
+	return nil
+}
+
+// result is a processed record with its position in the Source.
+type result[U any] struct {
+	seq   uint64
+	value U
+	skip  bool
+}
+
+// Run reads every record from source, processes them with stage and writes them to sink.
+// It blocks until the source is exhausted, the pipeline is aborted or ctx is cancelled.
+func Run[T, U any](ctx context.Context, source Source[T], stage Stage[T, U], sink Sink[U], opts Options) error {
+	if opts.Workers < 0 {
+		return ErrInvalidWorkers
+	}
+	if opts.Workers == 0 {
+		opts.Workers = runtime.NumCPU()
+	}
+	if opts.Window <= 0 {
+		opts.Window = opts.Workers * 2
+	}
+	if opts.OnError == nil {
+		opts.OnError = func(err error) error { return err }
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var once sync.Once
+	var firstErr, sourceErr error
+	abort := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	type task struct {
+		seq   uint64
+		value T
+	}
+	tasks := make(chan task)
+	results := make(chan result[U])
+	window := make(chan struct{}, opts.Window)
+
+	// input stream
+	go func() {
+		defer close(tasks)
+		for seq := uint64(0); ; seq++ {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			value, err := source.Next(ctx)
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				sourceErr = err
+				return
+			}
+			select {
+			case tasks <- task{seq: seq, value: value}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// workers
+	wg := sync.WaitGroup{}
+	wg.Add(opts.Workers)
+	for i := 0; i < opts.Workers; i++ {
+		go func() {
+			defer wg.Done()
+			for t := range tasks {
+				value, err := stage.Process(ctx, t.value)
+				r := result[U]{seq: t.seq, value: value}
+				if err != nil {
+					if !errors.Is(err, ErrSkip) {
+						if err = opts.OnError(err); err != nil {
+							abort(err)
+							return
+						}
+					}
+					r.skip = true
+				}
+				select {
+				case results <- r:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// output stream
+	write := func(r result[U]) {
+		<-window
+		if r.skip || ctx.Err() != nil {
+			return
+		}
+		if err := sink.Write(ctx, r.value); err != nil && !errors.Is(err, ErrSkip) {
+			if err = opts.OnError(err); err != nil {
+				abort(err)
+			}
+		}
+	}
+	pending := map[uint64]result[U]{}
+	next := uint64(0)
+	for r := range results {
+		if ctx.Err() != nil {
+			// aborted, discard the in-flight records
+			continue
+		}
+		if !opts.Ordered {
+			write(r)
+			continue
+		}
+		pending[r.seq] = r
+		for {
+			p, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			write(p)
+		}
+	}
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if sourceErr != nil {
+		return sourceErr
+	}
+	return parent.Err()
+}
+
+// FromChan returns a Source reading the records from ch until it is closed.
+func FromChan[T any](ch <-chan T) Source[T] {
+	return SourceFunc[T](func(ctx context.Context) (T, error) {
+		select {
+		case value, ok := <-ch:
+			if !ok {
+				return value, io.EOF
+			}
+			return value, nil
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+	})
+}
+
+// ToChan returns a Sink writing the records to ch. The caller is responsible to close ch after Run.
+func ToChan[U any](ch chan<- U) Sink[U] {
+	return SinkFunc[U](func(ctx context.Context, out U) error {
+		select {
+		case ch <- out:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// FromEmitter adapts a push style producer in to a Source, e.g. a parser calling back on each record.
+//
+// produce runs in its own goroutine once Next is first called, and must stop once emit returns an error,
+// which happens when the pipeline is aborted. The error returned by produce is returned by Next after
+// the emitted records. The Source must not be shared between pipelines.
+func FromEmitter[T any](produce func(emit func(T) error) error) Source[T] {
+	return &emitterSource[T]{produce: produce}
+}
+
+type emitterSource[T any] struct {
+	produce func(emit func(T) error) error
+	once    sync.Once
+	items   chan T
+	done    chan struct{}
+	err     error
+}
+
+// Next implements Source.
+func (s *emitterSource[T]) Next(ctx context.Context) (T, error) {
+	s.once.Do(func() {
+		s.items = make(chan T)
+		s.done = make(chan struct{})
+		go func() {
+			s.err = s.produce(func(item T) error {
+				select {
+				case s.items <- item:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			})
+			close(s.done)
+		}()
+	})
+
+	var zero T
+	select {
+	case item := <-s.items:
+		return item, nil
+	case <-s.done:
+		if s.err != nil {
+			return zero, s.err
+		}
+		return zero, io.EOF
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
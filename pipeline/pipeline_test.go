@@ -0,0 +1,253 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/keenangebze/go/pipeline"
+)
+
+// countSource emits 0 to n-1.
+func countSource(n int) pipeline.Source[int] {
+	i := 0
+	return pipeline.SourceFunc[int](func(ctx context.Context) (int, error) {
+		if i >= n {
+			return 0, io.EOF
+		}
+		i++
+		return i - 1, nil
+	})
+}
+
+// collect returns a Sink appending to the slice. The Sink is only called from one goroutine.
+func collect(out *[]string) pipeline.Sink[string] {
+	return pipeline.SinkFunc[string](func(ctx context.Context, s string) error {
+		*out = append(*out, s)
+		return nil
+	})
+}
+
+// Format numbers in parallel while keeping the ordering.
+func ExampleRun() {
+	numbers := make(chan int)
+	go func() {
+		for i := 1; i <= 5; i++ {
+			numbers <- i
+		}
+		close(numbers)
+	}()
+
+	square := pipeline.StageFunc[int, string](func(ctx context.Context, n int) (string, error) {
+		return fmt.Sprintf("%v^2 = %v", n, n*n), nil
+	})
+	print := pipeline.SinkFunc[string](func(ctx context.Context, s string) error {
+		fmt.Println(s)
+		return nil
+	})
+
+	pipeline.Run[int, string](context.Background(), pipeline.FromChan(numbers), square, print, pipeline.Options{Workers: 3, Ordered: true})
+
+	// Output:
+	//1^2 = 1
+	//2^2 = 4
+	//3^2 = 9
+	//4^2 = 16
+	//5^2 = 25
+}
+
+// TestOrdered asserts the ordering is kept even if the later records finish first.
+func TestOrdered(t *testing.T) {
+	stage := pipeline.StageFunc[int, string](func(ctx context.Context, n int) (string, error) {
+		time.Sleep(time.Duration(10-n%10) * time.Millisecond)
+		return strconv.Itoa(n), nil
+	})
+	var actual []string
+	err := pipeline.Run[int, string](context.Background(), countSource(50), stage, collect(&actual), pipeline.Options{Workers: 8, Ordered: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range actual {
+		if s != strconv.Itoa(i) {
+			t.Fatalf("Expected ordered result returned %v.\n", actual)
+		}
+	}
+	if len(actual) != 50 {
+		t.Fatalf("Expected 50 records returned %v.\n", len(actual))
+	}
+}
+
+// TestSkip asserts ErrSkip and OnError drop the record without aborting, also in ordered mode and from the sink.
+func TestSkip(t *testing.T) {
+	errOdd := errors.New("odd")
+	stage := pipeline.StageFunc[int, string](func(ctx context.Context, n int) (string, error) {
+		switch {
+		case n%3 == 0:
+			return "", pipeline.ErrSkip
+		case n%2 == 1:
+			return "", errOdd
+		}
+		return strconv.Itoa(n), nil
+	})
+	for _, ordered := range []bool{true, false} {
+		var actual []string
+		err := pipeline.Run[int, string](context.Background(), countSource(10), stage, collect(&actual), pipeline.Options{
+			Workers: 4,
+			Ordered: ordered,
+			OnError: pipeline.SkipErrors,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(actual)
+		if fmt.Sprint(actual) != "[2 4 8]" {
+			t.Fatalf("Expected [2 4 8] returned %v.\n", actual)
+		}
+	}
+
+	// the sink errors are skipped alike
+	var actual []string
+	sink := pipeline.SinkFunc[string](func(ctx context.Context, s string) error {
+		switch s {
+		case "0":
+			return pipeline.ErrSkip
+		case "4":
+			return errOdd
+		}
+		actual = append(actual, s)
+		return nil
+	})
+	identity := pipeline.StageFunc[int, string](func(ctx context.Context, n int) (string, error) {
+		return strconv.Itoa(n), nil
+	})
+	err := pipeline.Run[int, string](context.Background(), countSource(6), identity, sink, pipeline.Options{
+		Workers: 2,
+		Ordered: true,
+		OnError: pipeline.SkipErrors,
+	})
+	if err != nil || fmt.Sprint(actual) != "[1 2 3 5]" {
+		t.Fatalf("Expected [1 2 3 5] returned %v %v.\n", actual, err)
+	}
+}
+
+// TestAbort asserts the first error from each part of the pipeline is returned and stops the source.
+func TestAbort(t *testing.T) {
+	errBroken := errors.New("broken")
+	identity := pipeline.StageFunc[int, string](func(ctx context.Context, n int) (string, error) {
+		return strconv.Itoa(n), nil
+	})
+	infinite := func() pipeline.Source[int] {
+		var read int64
+		return pipeline.SourceFunc[int](func(ctx context.Context) (int, error) {
+			return int(atomic.AddInt64(&read, 1)), nil
+		})
+	}
+
+	cases := []struct {
+		name   string
+		source pipeline.Source[int]
+		stage  pipeline.Stage[int, string]
+		sink   pipeline.Sink[string]
+	}{
+		{
+			name: "source",
+			source: pipeline.SourceFunc[int](func(ctx context.Context) (int, error) {
+				return 0, errBroken
+			}),
+			stage: identity,
+			sink:  collect(new([]string)),
+		},
+		{
+			name:   "stage",
+			source: infinite(),
+			stage: pipeline.StageFunc[int, string](func(ctx context.Context, n int) (string, error) {
+				if n == 100 {
+					return "", errBroken
+				}
+				return "", nil
+			}),
+			sink: collect(new([]string)),
+		},
+		{
+			name:   "sink",
+			source: infinite(),
+			stage:  identity,
+			sink: pipeline.SinkFunc[string](func(ctx context.Context, s string) error {
+				if s == "100" {
+					return errBroken
+				}
+				return nil
+			}),
+		},
+	}
+	for _, c := range cases {
+		err := pipeline.Run(context.Background(), c.source, c.stage, c.sink, pipeline.Options{Workers: 4})
+		if !errors.Is(err, errBroken) {
+			t.Errorf("%v: expected errBroken returned %v.\n", c.name, err)
+		}
+	}
+}
+
+func TestCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	infinite := pipeline.SourceFunc[int](func(ctx context.Context) (int, error) {
+		return 1, nil
+	})
+	count := 0
+	sink := pipeline.SinkFunc[int](func(ctx context.Context, n int) error {
+		count++
+		if count == 10 {
+			cancel()
+		}
+		return nil
+	})
+	identity := pipeline.StageFunc[int, int](func(ctx context.Context, n int) (int, error) {
+		return n, nil
+	})
+
+	err := pipeline.Run[int, int](ctx, infinite, identity, sink, pipeline.Options{Workers: 2})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled returned %v.\n", err)
+	}
+}
+
+// TestFromEmitter asserts the emitted records are written before the producer error is returned.
+func TestFromEmitter(t *testing.T) {
+	errBroken := errors.New("broken")
+	source := pipeline.FromEmitter(func(emit func(string) error) error {
+		for _, s := range []string{"a", "b", "c"} {
+			if err := emit(s); err != nil {
+				return err
+			}
+		}
+		return errBroken
+	})
+	identity := pipeline.StageFunc[string, string](func(ctx context.Context, s string) (string, error) {
+		return s, nil
+	})
+	var actual []string
+
+	err := pipeline.Run[string, string](context.Background(), source, identity, collect(&actual), pipeline.Options{Workers: 2, Ordered: true})
+	if !errors.Is(err, errBroken) {
+		t.Fatalf("Expected errBroken returned %v.\n", err)
+	}
+	if fmt.Sprint(actual) != "[a b c]" {
+		t.Fatalf("Expected [a b c] returned %v.\n", actual)
+	}
+}
+
+func TestInvalidWorkers(t *testing.T) {
+	identity := pipeline.StageFunc[int, string](func(ctx context.Context, n int) (string, error) {
+		return "", nil
+	})
+	err := pipeline.Run[int, string](context.Background(), countSource(1), identity, collect(new([]string)), pipeline.Options{Workers: -1})
+	if err != pipeline.ErrInvalidWorkers {
+		t.Fatalf("Expected ErrInvalidWorkers returned %v.\n", err)
+	}
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/keenangebze/go/pipeline"
+	"github.com/keenangebze/go/util/csv2jsonl"
+)
+
+func init() {
+	csv2jsonlCmd.Flags().StringToStringVarP(&csv2jsonlParam.types, "type", "t", nil, "Column type coercion, e.g. year=int,price=float (string, int, float, bool, json)")
+	csv2jsonlCmd.Flags().IntVarP(&csv2jsonlParam.pipeline.Workers, "workers", "w", csv2jsonl.NumOfWorker, "The number of goroutine used to convert the rows")
+	csv2jsonlCmd.Flags().BoolVarP(&csv2jsonlParam.pipeline.Ordered, "ordered", "", false, "Maintain the row ordering in the output")
+
+	rootCmd.AddCommand(csv2jsonlCmd)
+}
+
+type csv2jsonlParameter struct {
+	types    map[string]string
+	pipeline pipeline.Options
+}
+
+var csv2jsonlParam csv2jsonlParameter
+
+var csv2jsonlCmd = &cobra.Command{
+	Use:   "csv2jsonl [file]",
+	Short: "Read CSV with header from file or STDIN, output them as line separated JSON (JSONL)",
+	Long: `Read CSV with header from file or STDIN, output them as line separated JSON (JSONL).
+	The header is used as the keys, dotted header such as "shop.name" becomes a nested object.
+	The output can be fed to "tkpd es docs2index". Row ordering is not maintained unless --ordered is set.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		types := map[string]csv2jsonl.Coercer{}
+		for column, typeName := range csv2jsonlParam.types {
+			coerce, ok := csv2jsonl.Coercers[typeName]
+			if !ok {
+				return fmt.Errorf("unknown type %q for column %q", typeName, column)
+			}
+			types[column] = coerce
+		}
+
+		in, err := openInput(args)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		csv2jsonlParam.pipeline.OnError = func(err error) error {
+			log.Printf("Cannot convert %v\n", err)
+			return nil
+		}
+		return csv2jsonl.Convert(cmd.Context(), in, os.Stdout, types, csv2jsonlParam.pipeline)
+	},
+}
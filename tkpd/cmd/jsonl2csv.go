@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/keenangebze/go/pipeline"
+	"github.com/keenangebze/go/util/jsonl2csv"
+	"github.com/keenangebze/go/util/parquet"
+)
+
+func init() {
+	jsonl2csvCmd.Flags().StringArrayVarP(&jsonl2csvParam.fields, "fields", "f", nil, `Field expression for each column, e.g. -f id -f shop.name -f "images[0].url // none" -f "len(tags)"`)
+	jsonl2csvCmd.Flags().BoolVarP(&jsonl2csvParam.header, "header", "", false, "Write the field expressions as the CSV header")
+	jsonl2csvCmd.Flags().StringVarP(&jsonl2csvParam.inputFormat, "input-format", "", "jsonl", "The input format: jsonl (one document per line) or json (JSON array or concatenated JSON values)")
+	jsonl2csvCmd.Flags().StringVarP(&jsonl2csvParam.path, "path", "", "", `The path of the documents inside each JSON value, e.g. "hits.hits" for ES responses (implies --input-format json)`)
+	jsonl2csvCmd.Flags().StringVarP(&jsonl2csvParam.outputFormat, "output-format", "", "csv", "The output format: csv or parquet (column types are inferred)")
+	jsonl2csvCmd.Flags().StringVarP(&jsonl2csvParam.parquet.Compression, "compression", "", "snappy", "The parquet compression: none, snappy, gzip or zstd")
+	jsonl2csvCmd.Flags().Int64VarP(&jsonl2csvParam.parquet.RowGroupSize, "row-group-size", "", 128*1024*1024, "The approximate parquet row group size in bytes")
+	jsonl2csvCmd.Flags().IntVarP(&jsonl2csvParam.parquet.InferRows, "infer-rows", "", 1000, "The number of rows buffered to infer the parquet column types")
+	jsonl2csvCmd.Flags().IntVarP(&jsonl2csvParam.pipeline.Workers, "workers", "w", jsonl2csv.NumOfWorker, "The number of goroutine used to convert the lines")
+	jsonl2csvCmd.Flags().BoolVarP(&jsonl2csvParam.pipeline.Ordered, "ordered", "", false, "Maintain the input ordering in the output")
+
+	rootCmd.AddCommand(jsonl2csvCmd)
+}
+
+type jsonl2csvParameter struct {
+	fields      []string
+	header      bool
+	inputFormat string
+	path        string
+	pipeline    pipeline.Options
+
+	outputFormat string
+	parquet      parquet.Options
+}
+
+var jsonl2csvParam jsonl2csvParameter
+
+var jsonl2csvCmd = &cobra.Command{
+	Use:   "jsonl2csv [file]",
+	Short: "Read line separated JSON (JSONL) from file or STDIN, output the selected fields as CSV or Parquet",
+	Long: `Read line separated JSON (JSONL) from file or STDIN, output the selected fields as CSV.
+	JSON arrays, concatenated JSON values and ES responses can be read with --input-format json and --path.
+	Each --fields expression becomes a column. Missing fields are written as the default value after "//", or empty.
+	With --output-format parquet, the expressions are used as the column names and the column types are inferred
+	from the first --infer-rows rows only: a later row which does not parse as the inferred type, e.g. a text
+	in a column of numbers, is logged and skipped. Raise --infer-rows when a column is sparse or mixed.
+	Row ordering is not maintained unless --ordered is set.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(jsonl2csvParam.fields) == 0 {
+			return errors.New("at least one --fields is required")
+		}
+		transform, err := jsonl2csv.Fields(jsonl2csvParam.fields...)
+		if err != nil {
+			return err
+		}
+
+		split := jsonl2csv.SplitLines
+		switch {
+		case jsonl2csvParam.path != "" || jsonl2csvParam.inputFormat == "json":
+			split, err = jsonl2csv.SplitJSON(jsonl2csvParam.path)
+			if err != nil {
+				return err
+			}
+		case jsonl2csvParam.inputFormat != "jsonl":
+			return fmt.Errorf("unknown input format %q", jsonl2csvParam.inputFormat)
+		}
+
+		in, err := openInput(args)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		ctx := cmd.Context()
+		jsonl2csvParam.pipeline.OnError = func(err error) error {
+			log.Printf("Cannot convert %v\n", err)
+			return nil
+		}
+		switch jsonl2csvParam.outputFormat {
+		case "csv":
+			csvWriter := csv.NewWriter(os.Stdout)
+			if jsonl2csvParam.header {
+				csvWriter.Write(jsonl2csvParam.fields)
+			}
+			err := jsonl2csv.Convert(ctx, in, csvWriter, split, transform, jsonl2csvParam.pipeline)
+			csvWriter.Flush()
+			if err != nil {
+				return err
+			}
+			return csvWriter.Error()
+		case "parquet":
+			parquetWriter, err := parquet.NewWriter(os.Stdout, parquet.Columns(jsonl2csvParam.fields...), jsonl2csvParam.parquet)
+			if err != nil {
+				return err
+			}
+			err = jsonl2csv.Convert(ctx, in, parquetWriter, split, transform, jsonl2csvParam.pipeline)
+			// the footer is written even if the conversion failed, so the rows already written stay readable
+			if closeErr := parquetWriter.Close(); err == nil {
+				err = closeErr
+			}
+			return err
+		default:
+			return fmt.Errorf("unknown output format %q", jsonl2csvParam.outputFormat)
+		}
+	},
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/keenangebze/go/internal/pkg/redis"
+)
+
+func init() {
+	redisAnalyzeCmd.Flags().StringVarP(&redisScanParam.matchPattern, "match", "m", "*", "Redis key scan pattern")
+	redisAnalyzeCmd.Flags().Int64VarP(&redisScanParam.scanSize, "scan-size", "", 10000, "The SCAN COUNT hint")
+	redisAnalyzeCmd.Flags().StringVarP(&redisAnalyzeParam.Delimiter, "delimiter", "d", ":", "The delimiter of the key segments grouped in to prefixes")
+	redisAnalyzeCmd.Flags().IntVarP(&redisAnalyzeParam.Depth, "depth", "", 1, "The number of key segments making a prefix")
+	redisAnalyzeCmd.Flags().IntVarP(&redisAnalyzeParam.MaxPrefixes, "max-prefixes", "", 10000, "The number of prefixes counted, the keys of the later ones are grouped under (other)")
+	redisAnalyzeCmd.Flags().Float64VarP(&redisAnalyzeParam.SampleRate, "sample-rate", "", 1, "The fraction of the scanned keys analyzed, e.g. 0.01 (1 means every key)")
+	redisAnalyzeCmd.Flags().Int64VarP(&redisAnalyzeParam.MaxKeys, "max-keys", "", 0, "Stop after analyzing that many keys on each node (0 means no limit)")
+	redisAnalyzeCmd.Flags().IntVarP(&redisAnalyzeParam.Top, "top", "", 10, "The number of biggest keys and prefixes reported")
+	redisAnalyzeCmd.Flags().StringVarP(&redisAnalyzeOutput, "output", "o", "table", "The report format: table or json")
+
+	redisCmd.AddCommand(redisAnalyzeCmd)
+}
+
+var redisAnalyzeParam redis.AnalyzeOptions
+var redisAnalyzeOutput string
+
+var redisAnalyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "Report the memory and cardinality of the keys by prefix, type and TTL (MEMORY USAGE, TYPE, TTL)",
+	Long: `Report the memory and cardinality of the keys by prefix, type and TTL.
+	The keys are scanned, or sampled with --sample-rate and --max-keys, then MEMORY USAGE, TYPE, TTL
+	and the length of each key are pipelined. The keys are grouped by the first --depth segments split by --delimiter,
+	the keys without --delimiter under (none), and the keys beyond --max-prefixes prefixes under (other).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if redisAnalyzeOutput != "table" && redisAnalyzeOutput != "json" {
+			return fmt.Errorf("unknown output %q, must be table or json", redisAnalyzeOutput)
+		}
+		nodes, err := redisNodes()
+		if err != nil {
+			return err
+		}
+
+		mu := sync.Mutex{}
+		report := redis.NewReport(redisAnalyzeParam.Top, redisAnalyzeParam.MaxPrefixes)
+		err = redis.ForEachNode(nodes, redisParam.concurrency, func(node redis.Config) error {
+			client := redis.NewClient(node)
+			defer client.Close()
+			nodeReport, err := client.Analyze(cmd.Context(), redisScanOptions(), redisAnalyzeParam)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			report.Merge(nodeReport)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if redisAnalyzeOutput == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(report)
+		}
+		return writeReport(os.Stdout, report, redisAnalyzeParam.Top)
+	},
+}
+
+// writeReport writes the report as tables, with the top prefixes.
+func writeReport(out io.Writer, report *redis.Report, top int) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "scanned\tanalyzed\tmemory\telements\t\n")
+	fmt.Fprintf(w, "%v\t%v\t%v\t%v\t\n\n", report.Scanned, report.Total.Keys, report.Total.Memory, report.Total.Elements)
+
+	fmt.Fprintf(w, "prefix\tkeys\tmemory\telements\t\n")
+	for i, prefix := range report.SortedPrefixes() {
+		if i == top {
+			break
+		}
+		usage := report.Prefixes[prefix]
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t\n", prefix, usage.Keys, usage.Memory, usage.Elements)
+	}
+
+	fmt.Fprintf(w, "\nbiggest key\ttype\tmemory\telements\tttl\t\n")
+	for _, key := range report.Biggest {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t\n", key.Key, key.Type, key.Memory, key.Elements, key.TTL)
+	}
+
+	fmt.Fprintf(w, "\ntype\tkeys\tmemory\telements\t\n")
+	for _, keyType := range []string{"string", "list", "set", "zset", "hash", "stream"} {
+		if usage, ok := report.Types[keyType]; ok {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t\n", keyType, usage.Keys, usage.Memory, usage.Elements)
+		}
+	}
+
+	fmt.Fprintf(w, "\nttl\tkeys\t\n")
+	fmt.Fprintf(w, "none\t%v\t\n", report.TTL["none"])
+	for _, bucket := range redis.TTLBuckets {
+		fmt.Fprintf(w, "%v\t%v\t\n", bucket.Name, report.TTL[bucket.Name])
+	}
+	return w.Flush()
+}
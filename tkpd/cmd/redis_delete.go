@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"sync/atomic"
+
+	"github.com/spf13/cobra"
+
+	"github.com/keenangebze/go/internal/pkg/redis"
+)
+
+func init() {
+	redisDeleteCmd.Flags().StringVarP(&redisDeleteParam.match, "match", "m", "", "Redis key scan pattern of the keys to delete")
+	redisDeleteCmd.Flags().StringVarP(&redisScanParam.exactKeys, "keys", "k", "", "Exact keys to delete (will ignore match flag)")
+	redisDeleteCmd.Flags().StringVarP(&redisScanParam.keysFile, "keys-file", "", "", `File of the exact keys to delete, one key per line, "-" for STDIN (will ignore match flag)`)
+	redisDeleteCmd.Flags().Int64VarP(&redisScanParam.scanSize, "scan-size", "", 10000, "The SCAN COUNT hint")
+	redisDeleteCmd.Flags().IntVarP(&redisScanParam.batchSize, "batch-size", "", 1000, "The maximum number of keys unlinked in a single pipeline")
+	redisDeleteCmd.Flags().BoolVarP(&redisDeleteParam.yes, "yes", "", false, "Delete the keys, otherwise only count them")
+	redisDeleteCmd.Flags().StringVarP(&redisDeleteParam.backup, "backup", "", "", "Dump the keys to this file before deleting them, to undo with populate all")
+	redisDeleteCmd.Flags().StringVarP(&redisDeleteParam.backupFormat, "backup-format", "", "binary", "The format of the backup: csv, jsonl or binary, the csv format does not keep the TTL of the keys")
+
+	redisCmd.AddCommand(redisDeleteCmd)
+}
+
+type redisDeleteParameter struct {
+	match        string
+	yes          bool
+	backup       string
+	backupFormat string
+}
+
+var redisDeleteParam redisDeleteParameter
+
+var redisDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete the keys matching a pattern or given exactly (UNLINK)",
+	Long: `Delete the keys matching --match, or given by --keys or --keys-file, using UNLINK in pipelines of --batch-size keys.
+	Use --rate-limit to throttle the deletion. Without --yes, the keys are only counted.
+	With --backup, the keys are dumped to the file with their TTL before being deleted, the keys not dumped are kept.
+	Each page of keys is written and synced to the file before its keys are deleted, e.g.
+	tkpd redis delete -m "stale:*" --yes --backup stale.bin
+	tkpd redis populate all --input-format binary stale.bin`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if redisDeleteParam.match == "" && redisScanParam.exactKeys == "" && redisScanParam.keysFile == "" {
+			return errors.New("--match, --keys or --keys-file is required")
+		}
+		if _, err := redis.NewEncoder(redisDeleteParam.backupFormat, io.Discard, true); err != nil {
+			return err
+		}
+		nodes, err := redisNodes()
+		if err != nil {
+			return err
+		}
+		opts := redisScanOptions()
+		opts.Match = redisDeleteParam.match
+		keys, err := openKeysFile(&opts)
+		if err != nil {
+			return err
+		}
+		defer keys.Close()
+
+		var file *os.File
+		var backup *redis.SyncWriter
+		if redisDeleteParam.backup != "" && redisDeleteParam.yes {
+			file, err = os.Create(redisDeleteParam.backup)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			backup = redis.NewSyncWriter(file)
+		}
+
+		var total int64
+		err = redis.ForEachNode(nodes, redisParam.concurrency, func(node redis.Config) error {
+			client := redis.NewClient(node)
+			defer client.Close()
+			del := redis.DeleteOptions{DryRun: !redisDeleteParam.yes}
+			var sink redis.Encoder
+			if backup != nil {
+				encoder, _ := redis.NewEncoder(redisDeleteParam.backupFormat, backup, true)
+				sink = syncedEncoder{Encoder: encoder, file: file}
+				del.Backup = sink
+			}
+			n, err := client.Delete(cmd.Context(), opts, del)
+			atomic.AddInt64(&total, n)
+			if sink != nil {
+				if flushErr := sink.Flush(); err == nil {
+					err = flushErr
+				}
+			}
+			return err
+		})
+		if !redisDeleteParam.yes {
+			log.Printf("%v keys to delete, run again with --yes to delete them\n", total)
+		} else {
+			log.Printf("deleted %v keys\n", total)
+		}
+		return logScanProgress(err, opts)
+	},
+}
+
+// syncedEncoder syncs the backup file once flushed, so the keys of a page are deleted once their backup is on disk.
+type syncedEncoder struct {
+	redis.Encoder
+	file *os.File
+}
+
+// Flush implements redis.Encoder.
+func (e syncedEncoder) Flush() error {
+	if err := e.Encoder.Flush(); err != nil {
+		return err
+	}
+	return e.file.Sync()
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/keenangebze/go/internal/pkg/redis"
+	"github.com/keenangebze/go/pipeline"
+)
+
+func init() {
+	redisDiffCmd.Flags().StringVarP(&redisDiffParam.to.Address, "to", "", "", "The <HOST>:<PORT> of the redis compared with --host and --port")
+	redisDiffCmd.Flags().StringVarP(&redisDiffParam.to.Password, "to-password", "", "", "The authentication password for the compared redis")
+	redisDiffCmd.Flags().BoolVarP(&redisDiffParam.to.Cluster, "to-cluster", "", false, "Treat the compared redis as a seed of a redis cluster")
+	redisDiffCmd.Flags().StringVarP(&redisDiffParam.toURL, "to-url", "", "", "The redis:// or rediss:// URL of the compared redis, replacing --to and --to-password")
+	redisDiffCmd.Flags().StringVarP(&redisDiffParam.file, "file", "f", "", `The dump compared with --host and --port instead of --to, written by dump all, "-" for STDIN`)
+	redisDiffCmd.Flags().StringVarP(&redisDiffParam.inputFormat, "input-format", "", "csv", "The format of --file: csv, jsonl or binary")
+	redisDiffCmd.Flags().StringVarP(&redisScanParam.matchPattern, "match", "m", "*", "Redis key scan pattern")
+	redisDiffCmd.Flags().StringVarP(&redisScanParam.exactKeys, "keys", "k", "", "Exact keys to compare (will ignore match flag)")
+	redisDiffCmd.Flags().StringVarP(&redisScanParam.keysFile, "keys-file", "", "", `File of the exact keys to compare, one key per line, "-" for STDIN (will ignore match flag)`)
+	redisDiffCmd.Flags().Int64VarP(&redisScanParam.scanSize, "scan-size", "", 10000, "The SCAN COUNT hint, also the number of elements above which a value is compared chunk by chunk")
+	redisDiffCmd.Flags().IntVarP(&redisScanParam.batchSize, "batch-size", "", 1000, "The maximum number of keys compared in a single pipeline")
+	redisDiffCmd.Flags().BoolVarP(&redisDiffParam.extra, "extra", "", true, "Also scan the compared side for the keys matching --match missing from the source")
+	redisDiffCmd.Flags().StringVarP(&redisDiffParam.output, "output", "o", "-", `The JSONL file of the missing, extra and changed keys, "-" for STDOUT`)
+
+	redisCmd.AddCommand(redisDiffCmd)
+}
+
+type redisDiffParameter struct {
+	to          redis.Config
+	toURL       string
+	file        string
+	inputFormat string
+	extra       bool
+	output      string
+}
+
+var redisDiffParam redisDiffParameter
+
+var redisDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare the keys and values of two redis, or of a dump and a redis",
+	Long: `Compare the keys matching --match, or given by --keys or --keys-file, of the redis of --host and --port with --to,
+	or compare the dump --file with the redis of --host and --port. The values are compared by type:
+	the order of the members of a set, the fields of a hash and the members of a sorted set with the same score is ignored.
+	Each missing, extra or changed key is written as a JSON line to --output, with the list indexes, members or fields
+	which differ, and the totals are logged. A value of more than --scan-size elements is compared chunk by chunk
+	instead of being read whole, e.g.
+	tkpd redis diff -m "user:*" --to 10.0.0.2:6379 -o diff.jsonl
+	tkpd redis dump all --output-format binary > users.bin && tkpd redis diff --file users.bin --input-format binary`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if (redisDiffParam.to.Address == "" && redisDiffParam.toURL == "") == (redisDiffParam.file == "") {
+			return errors.New("either --to, --to-url or --file is required")
+		}
+		if redisDiffParam.file != "" && (redisScanParam.exactKeys != "" || redisScanParam.keysFile != "") {
+			return errors.New("--keys and --keys-file cannot be used with --file")
+		}
+		if _, err := redis.NewDecoder(redisDiffParam.inputFormat, nil, ""); err != nil {
+			return err
+		}
+
+		out := io.WriteCloser(os.Stdout)
+		if redisDiffParam.output != "-" {
+			var err error
+			if out, err = os.Create(redisDiffParam.output); err != nil {
+				return err
+			}
+		}
+		defer out.Close()
+		w := bufio.NewWriter(out)
+		defer w.Flush()
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		mu := sync.Mutex{}
+		sink := pipeline.SinkFunc[redis.Difference](func(ctx context.Context, difference redis.Difference) error {
+			mu.Lock()
+			defer mu.Unlock()
+			return encoder.Encode(difference)
+		})
+
+		var stats redis.DiffStats
+		var err error
+		if redisDiffParam.file != "" {
+			err = diffFile(cmd.Context(), sink, &stats)
+		} else {
+			err = diffRedis(cmd.Context(), sink, &stats)
+		}
+		log.Printf("compared %v keys: %v missing, %v extra, %v changed\n", stats.Keys, stats.Missing, stats.Extra, stats.Changed)
+		return err
+	},
+}
+
+// diffRedis compares each node of --host and --port concurrently with --to, then scans --to for the extra keys.
+func diffRedis(ctx context.Context, sink pipeline.Sink[redis.Difference], stats *redis.DiffStats) error {
+	nodes, err := redisNodes()
+	if err != nil {
+		return err
+	}
+	opts := redisScanOptions()
+	keys, err := openKeysFile(&opts)
+	if err != nil {
+		return err
+	}
+	defer keys.Close()
+	toConfig, err := redisTargetConfig(redisDiffParam.to, redisDiffParam.toURL)
+	if err != nil {
+		return err
+	}
+	to := redis.NewClient(toConfig)
+	defer to.Close()
+
+	mu := sync.Mutex{}
+	err = redis.ForEachNode(nodes, redisParam.concurrency, func(node redis.Config) error {
+		client := redis.NewClient(node)
+		defer client.Close()
+		nodeStats, err := client.Diff(ctx, to, opts, sink)
+		mu.Lock()
+		defer mu.Unlock()
+		stats.Keys += nodeStats.Keys
+		stats.Missing += nodeStats.Missing
+		stats.Changed += nodeStats.Changed
+		return err
+	})
+	if err != nil || !redisDiffParam.extra || opts.Keys != nil || opts.KeyReader != nil {
+		return err
+	}
+
+	config, err := redisClientConfig()
+	if err != nil {
+		return err
+	}
+	source := redis.NewClient(config)
+	defer source.Close()
+	targets := []redis.Config{toConfig}
+	if toConfig.Cluster {
+		if targets, err = clusterMasters(toConfig); err != nil {
+			return err
+		}
+	}
+	return diffExtra(ctx, targets, opts, source.Exists, sink, stats)
+}
+
+// diffFile compares the dump with the redis of --host and --port, then scans the nodes for the extra keys.
+func diffFile(ctx context.Context, sink pipeline.Sink[redis.Difference], stats *redis.DiffStats) error {
+	in, err := openInput([]string{redisDiffParam.file})
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	dump, err := redis.NewDecoder(redisDiffParam.inputFormat, in, "")
+	if err != nil {
+		return err
+	}
+	config, err := redisClientConfig()
+	if err != nil {
+		return err
+	}
+	client := redis.NewClient(config)
+	defer client.Close()
+	opts := redisScanOptions()
+	fileStats, keys, err := client.DiffEntries(ctx, dump, opts, sink)
+	*stats = fileStats
+	if err != nil || !redisDiffParam.extra {
+		return err
+	}
+
+	nodes, err := redisNodes()
+	if err != nil {
+		return err
+	}
+	return diffExtra(ctx, nodes, opts, keys.Exists, sink, stats)
+}
+
+// diffExtra scans the nodes concurrently for the keys which are not in the source.
+func diffExtra(ctx context.Context, nodes []redis.Config, opts redis.ScanOptions, exists func(keys []string) ([]bool, error), sink pipeline.Sink[redis.Difference], stats *redis.DiffStats) error {
+	mu := sync.Mutex{}
+	return redis.ForEachNode(nodes, redisParam.concurrency, func(node redis.Config) error {
+		client := redis.NewClient(node)
+		defer client.Close()
+		extra, err := client.DiffExtra(ctx, opts, exists, sink)
+		mu.Lock()
+		defer mu.Unlock()
+		stats.Extra += extra
+		return err
+	})
+}
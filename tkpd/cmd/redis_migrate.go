@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/keenangebze/go/internal/pkg/redis"
+)
+
+func init() {
+	redisMigrateCmd.Flags().StringVarP(&redisMigrateParam.from, "from", "", "", "The <HOST>:<PORT> of the source redis, default to --host and --port")
+	redisMigrateCmd.Flags().StringVarP(&redisMigrateParam.to.Address, "to", "", "", "The <HOST>:<PORT> of the target redis")
+	redisMigrateCmd.Flags().StringVarP(&redisMigrateParam.to.Password, "to-password", "", "", "The authentication password for the target redis")
+	redisMigrateCmd.Flags().BoolVarP(&redisMigrateParam.to.Cluster, "to-cluster", "", false, "Treat the target as a seed of a redis cluster")
+	redisMigrateCmd.Flags().StringVarP(&redisMigrateParam.toURL, "to-url", "", "", "The redis:// or rediss:// URL of the target redis, replacing --to and --to-password")
+	redisMigrateCmd.Flags().StringVarP(&redisScanParam.matchPattern, "match", "m", "*", "Redis key scan pattern")
+	redisMigrateCmd.Flags().StringVarP(&redisScanParam.exactKeys, "keys", "k", "", "Exact keys to migrate (will ignore match flag)")
+	redisMigrateCmd.Flags().StringVarP(&redisScanParam.keysFile, "keys-file", "", "", `File of the exact keys to migrate, one key per line, "-" for STDIN (will ignore match flag)`)
+	redisMigrateCmd.Flags().Int64VarP(&redisScanParam.scanSize, "scan-size", "", 10000, "The SCAN COUNT hint")
+	redisMigrateCmd.Flags().IntVarP(&redisScanParam.batchSize, "batch-size", "", 1000, "The maximum number of keys migrated in a single pipeline")
+	redisMigrateCmd.Flags().BoolVarP(&redisMigrateParam.migrate.Replace, "replace", "", false, "Replace the keys existing in the target, otherwise they are skipped")
+	redisMigrateCmd.Flags().IntVarP(&redisMigrateParam.migrate.Workers, "workers", "", 4, "The number of pipelines migrated concurrently on each node")
+	redisMigrateCmd.Flags().StringVarP(&redisMigrateParam.checkpoint, "checkpoint", "", "", "The file of the SCAN cursor of each node, written while migrating and read to resume an interrupted migration")
+	redisMigrateCmd.Flags().BoolVarP(&redisMigrateParam.verify, "verify", "", true, "Check every key exists in the target once migrated")
+	redisMigrateCmd.Flags().Float64VarP(&redisMigrateParam.verifySampleRate, "verify-sample-rate", "", 0.01, "The fraction of the keys whose values are compared by the verification")
+
+	redisCmd.AddCommand(redisMigrateCmd)
+}
+
+type redisMigrateParameter struct {
+	from             string
+	to               redis.Config
+	toURL            string
+	migrate          redis.MigrateOptions
+	checkpoint       string
+	verify           bool
+	verifySampleRate float64
+}
+
+var redisMigrateParam redisMigrateParameter
+
+// migrateCheckpoint is the progress of the migration of a node.
+type migrateCheckpoint struct {
+	Cursor uint64 `json:"cursor"`
+	Done   bool   `json:"done"`
+}
+
+var redisMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Copy the keys to another redis, keeping their TTL (DUMP and RESTORE)",
+	Long: `Copy the keys matching --match, or given by --keys or --keys-file, from --from to --to using DUMP and RESTORE.
+	The keys existing in the target are skipped unless --replace is set. Use --rate-limit to throttle the migration.
+	With --checkpoint, the SCAN cursor of each node is saved as the pages are migrated,
+	and an interrupted migration is resumed from it by running the same command again.
+	Once migrated, every key is checked to exist in the target, and a --verify-sample-rate fraction of them is compared.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		toConfig, err := redisTargetConfig(redisMigrateParam.to, redisMigrateParam.toURL)
+		if err != nil {
+			return err
+		}
+		if redisMigrateParam.from != "" {
+			host, port, err := net.SplitHostPort(redisMigrateParam.from)
+			if err != nil {
+				return err
+			}
+			if redisParam.port, err = strconv.Atoi(port); err != nil {
+				return err
+			}
+			redisParam.host = host
+		}
+		nodes, err := redisNodes()
+		if err != nil {
+			return err
+		}
+		opts := redisScanOptions()
+		keys, err := openKeysFile(&opts)
+		if err != nil {
+			return err
+		}
+		defer keys.Close()
+		checkpoints, err := readCheckpoints(redisMigrateParam.checkpoint)
+		if err != nil {
+			return err
+		}
+
+		to := redis.NewClient(toConfig)
+		defer to.Close()
+		mu := sync.Mutex{}
+		return redis.ForEachNode(nodes, redisParam.concurrency, func(node redis.Config) error {
+			mu.Lock()
+			checkpoint := checkpoints[node.Address]
+			mu.Unlock()
+			if checkpoint.Done {
+				log.Println(node.Address, "already migrated")
+				return nil
+			}
+
+			client := redis.NewClient(node)
+			defer client.Close()
+			nodeOpts := opts
+			if checkpoint.Cursor != 0 {
+				nodeOpts.Cursor = checkpoint.Cursor
+			}
+			migrate := redisMigrateParam.migrate
+			if redisMigrateParam.checkpoint != "" {
+				migrate.Checkpoint = func(cursor uint64, done bool) error {
+					mu.Lock()
+					defer mu.Unlock()
+					checkpoints[node.Address] = migrateCheckpoint{Cursor: cursor, Done: done}
+					return writeCheckpoints(redisMigrateParam.checkpoint, checkpoints)
+				}
+			}
+			stats, err := client.Migrate(cmd.Context(), to, nodeOpts, migrate)
+			log.Printf("%v: migrated %v, skipped %v keys\n", node.Address, stats.Migrated, stats.Skipped)
+			if err != nil || !redisMigrateParam.verify {
+				return err
+			}
+			if opts.KeyReader != nil {
+				log.Println("[WARN] The keys of --keys-file are read once, skipping the verification")
+				return nil
+			}
+
+			report, err := client.Verify(cmd.Context(), to, opts, redisMigrateParam.verifySampleRate)
+			if err != nil {
+				return err
+			}
+			log.Printf("%v: verified %v keys, %v missing, %v of %v sampled values mismatched %v\n",
+				node.Address, report.Keys, report.Missing, report.Mismatched, report.Sampled, report.Examples)
+			return nil
+		})
+	},
+}
+
+// readCheckpoints reads the checkpoint of each node by address, none if the file does not exist yet.
+func readCheckpoints(file string) (map[string]migrateCheckpoint, error) {
+	checkpoints := map[string]migrateCheckpoint{}
+	if file == "" {
+		return checkpoints, nil
+	}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return checkpoints, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return checkpoints, json.Unmarshal(data, &checkpoints)
+}
+
+// writeCheckpoints replaces the checkpoint file, renaming a temporary file so it is never half written.
+func writeCheckpoints(file string, checkpoints map[string]migrateCheckpoint) error {
+	data, err := json.Marshal(checkpoints)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(file+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(file+".tmp", file)
+}
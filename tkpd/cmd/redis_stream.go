@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/keenangebze/go/internal/pkg/redis"
+)
+
+func init() {
+	redisStreamCmd.AddCommand(redisStreamReplayCmd)
+	redisStreamCmd.AddCommand(redisStreamGroupsCmd)
+	redisStreamReplayCmd.Flags().StringVarP(&redisReplayInputFormat, "input-format", "", "jsonl", "The input format written by dump stream: csv, jsonl or binary")
+	redisStreamReplayCmd.Flags().StringVarP(&redisReplayParam.Stream, "stream", "s", "", "The stream the entries are added to (default to the dumped key)")
+	redisStreamReplayCmd.Flags().BoolVarP(&redisReplayParam.KeepIDs, "keep-ids", "", false, "Add the entries with their original IDs instead of new IDs")
+	redisStreamReplayCmd.Flags().Int64VarP(&redisReplayParam.MaxLen, "max-len", "", 0, "Cap the length of the stream approximately (XADD MAXLEN ~, 0 means no limit)")
+	redisStreamReplayCmd.Flags().IntVarP(&redisReplayParam.BatchSize, "batch-size", "", 1000, "The number of entries added in a single pipeline")
+	redisStreamGroupsCmd.Flags().StringVarP(&redisScanParam.matchPattern, "match", "m", "*", "Redis key scan pattern")
+	redisStreamGroupsCmd.Flags().StringVarP(&redisScanParam.exactKeys, "keys", "k", "", "Exact stream keys (will ignore match flag)")
+	redisStreamGroupsCmd.Flags().Int64VarP(&redisScanParam.scanSize, "scan-size", "", 10000, "The SCAN COUNT hint")
+	redisStreamGroupsCmd.Flags().StringVarP(&redisStreamGroupsOutput, "output", "o", "table", "The output format: table or json")
+
+	redisCmd.AddCommand(redisStreamCmd)
+}
+
+var redisReplayParam redis.ReplayOptions
+var redisReplayInputFormat string
+var redisStreamGroupsOutput string
+
+var redisStreamCmd = &cobra.Command{
+	Use:   "stream",
+	Short: "Replay the dumped entries of streams, or inspect their consumer groups",
+}
+
+var redisStreamReplayCmd = &cobra.Command{
+	Use:   "replay [file]",
+	Short: "Add the entries written by dump stream to a stream (XADD)",
+	Long: `Add the entries written by dump stream to the stream of the same key, or to --stream.
+	The entries get new IDs unless --keep-ids is set, then the entries not greater than the last ID of the stream are skipped, e.g.
+	tkpd redis dump stream -k orders > orders.jsonl
+	tkpd redis -h 10.0.0.2 stream replay --stream orders-copy --keep-ids orders.jsonl`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		in, err := openInput(args)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		source, err := redis.NewDecoder(redisReplayInputFormat, in, "stream")
+		if err != nil {
+			return err
+		}
+		config, err := redisClientConfig()
+		if err != nil {
+			return err
+		}
+		client := redis.NewClient(config)
+		defer client.Close()
+
+		stats, err := client.Replay(cmd.Context(), source, redisReplayParam)
+		log.Printf("added %v, skipped %v entries\n", stats.Added, stats.Skipped)
+		return err
+	},
+}
+
+var redisStreamGroupsCmd = &cobra.Command{
+	Use:   "groups",
+	Short: "Report the consumer groups of the streams and their pending entries (XINFO GROUPS, XPENDING)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if redisStreamGroupsOutput != "table" && redisStreamGroupsOutput != "json" {
+			return fmt.Errorf("unknown output %q, must be table or json", redisStreamGroupsOutput)
+		}
+		nodes, err := redisNodes()
+		if err != nil {
+			return err
+		}
+		mu := sync.Mutex{}
+		var groups []redis.StreamGroup
+		err = redis.ForEachNode(nodes, redisParam.concurrency, func(node redis.Config) error {
+			client := redis.NewClient(node)
+			defer client.Close()
+			nodeGroups, err := client.StreamGroups(cmd.Context(), redisScanOptions())
+			mu.Lock()
+			defer mu.Unlock()
+			groups = append(groups, nodeGroups...)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		sort.Slice(groups, func(i, j int) bool {
+			if groups[i].Stream != groups[j].Stream {
+				return groups[i].Stream < groups[j].Stream
+			}
+			return groups[i].Name < groups[j].Name
+		})
+
+		if redisStreamGroupsOutput == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(groups)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "stream\tgroup\tconsumers\tlast delivered\tlag\tpending\tlowest\thighest\tpending by consumer\t\n")
+		for _, group := range groups {
+			consumers := make([]string, 0, len(group.PendingByConsumer))
+			for consumer, n := range group.PendingByConsumer {
+				consumers = append(consumers, fmt.Sprintf("%v=%v", consumer, n))
+			}
+			sort.Strings(consumers)
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t\n", group.Stream, group.Name, group.Consumers, group.LastDeliveredID,
+				group.Lag, group.Pending, group.Lowest, group.Highest, strings.Join(consumers, ","))
+		}
+		return w.Flush()
+	},
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/keenangebze/go/internal/pkg/redis"
+	"github.com/keenangebze/go/pipeline"
+)
+
+func init() {
+	redisTailCmd.Flags().StringSliceVarP(&redisTailParam.Channels, "channel", "c", nil, "The channels to subscribe, a channel with *, ? or [ is a pattern (PSUBSCRIBE)")
+	redisTailCmd.Flags().StringVarP(&redisTailKeyspaceEvents, "keyspace-events", "e", "", `The keyspace notifications to tail, e.g. "set,del,expired" or "*" for every event`)
+	redisTailCmd.Flags().StringVarP(&redisTailParam.Match, "match", "m", "*", "The pattern of the keys of the keyspace notifications")
+	redisTailCmd.Flags().BoolVarP(&redisTailParam.FetchValues, "fetch-values", "", false, "Read the value of the key of each keyspace notification")
+
+	redisCmd.AddCommand(redisTailCmd)
+}
+
+var redisTailParam redis.TailOptions
+var redisTailKeyspaceEvents string
+
+var redisTailCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "Stream the messages of channels and the keyspace notifications as JSONL (SUBSCRIBE, PSUBSCRIBE)",
+	Long: `Stream the messages of --channel and the keyspace notifications of --keyspace-events to STDOUT, one JSON line each,
+	until interrupted. With --cluster, the keyspace notifications of every master are tailed.
+	The keyspace notifications must be enabled in redis, e.g. CONFIG SET notify-keyspace-events KEA.
+	tkpd redis tail --channel invalidate --keyspace-events set,del,expired --match "product:*" --fetch-values`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if redisTailKeyspaceEvents != "" {
+			redisTailParam.KeyspaceEvents = strings.Split(redisTailKeyspaceEvents, ",")
+		}
+		if len(redisTailParam.Channels) == 0 && len(redisTailParam.KeyspaceEvents) == 0 {
+			return errors.New("--channel or --keyspace-events is required")
+		}
+		nodes, err := redisNodes()
+		if err != nil {
+			return err
+		}
+
+		// interrupted by redisCmd
+		ctx, cancel := context.WithCancel(cmd.Context())
+		defer cancel()
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		mu := sync.Mutex{}
+		sink := pipeline.SinkFunc[redis.Event](func(ctx context.Context, event redis.Event) error {
+			mu.Lock()
+			defer mu.Unlock()
+			return encoder.Encode(event)
+		})
+
+		// the nodes are tailed together until one fails, the channels of a cluster are published to every node
+		return redis.ForEachNode(nodes, len(nodes), func(node redis.Config) error {
+			client := redis.NewClient(node)
+			defer client.Close()
+			opts := redisTailParam
+			if node.Address != nodes[0].Address {
+				opts.Channels = nil
+			}
+			if len(opts.Channels) == 0 && len(opts.KeyspaceEvents) == 0 {
+				return nil
+			}
+			err := client.Tail(ctx, opts, sink)
+			if err != nil {
+				cancel()
+			}
+			return err
+		})
+	},
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/keenangebze/go/internal/pkg/redis"
+)
+
+func init() {
+	redisTTLCmd.AddCommand(redisTTLReportCmd)
+	redisTTLCmd.AddCommand(redisTTLSetCmd)
+	redisTTLCmd.AddCommand(redisTTLPersistCmd)
+	redisTTLCmd.PersistentFlags().StringVarP(&redisScanParam.matchPattern, "match", "m", "*", "Redis key scan pattern")
+	redisTTLCmd.PersistentFlags().StringVarP(&redisScanParam.exactKeys, "keys", "k", "", "Exact keys (will ignore match flag)")
+	redisTTLCmd.PersistentFlags().StringVarP(&redisScanParam.keysFile, "keys-file", "", "", `File of the exact keys, one key per line, "-" for STDIN (will ignore match flag)`)
+	redisTTLCmd.PersistentFlags().Int64VarP(&redisScanParam.scanSize, "scan-size", "", 10000, "The SCAN COUNT hint")
+	redisTTLCmd.PersistentFlags().IntVarP(&redisScanParam.batchSize, "batch-size", "", 1000, "The maximum number of keys in a single pipeline")
+	redisTTLReportCmd.Flags().StringVarP(&redisTTLOutput, "output", "o", "table", "The report format: table or json")
+	redisTTLSetCmd.Flags().DurationVarP(&redisTTLParam.TTL, "ttl", "", 0, "The TTL set on each key, e.g. 720h")
+	redisTTLSetCmd.Flags().DurationVarP(&redisTTLParam.Jitter, "jitter", "", 0, "Add a random duration up to this to the TTL of each key, e.g. 24h, so they do not all expire at once")
+	redisTTLSetCmd.Flags().BoolVarP(&redisTTLParam.OnlyPersistent, "only-persistent", "", false, "Only set the TTL of the keys without expiry")
+
+	redisCmd.AddCommand(redisTTLCmd)
+}
+
+var redisTTLParam redis.ExpireOptions
+var redisTTLOutput string
+
+var redisTTLCmd = &cobra.Command{
+	Use:   "ttl",
+	Short: "Report, set or remove the TTL of the keys matching a pattern or given exactly",
+}
+
+var redisTTLReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Count the keys by TTL range, including the keys without expiry (TTL)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if redisTTLOutput != "table" && redisTTLOutput != "json" {
+			return fmt.Errorf("unknown output %q, must be table or json", redisTTLOutput)
+		}
+		mu := sync.Mutex{}
+		histogram := map[string]int64{}
+		_, err := runTTL(func(client *redis.Client, opts redis.ScanOptions) (int64, error) {
+			nodeHistogram, err := client.TTLHistogram(cmd.Context(), opts)
+			mu.Lock()
+			defer mu.Unlock()
+			for bucket, n := range nodeHistogram {
+				histogram[bucket] += n
+			}
+			return 0, err
+		})
+		if err != nil {
+			return err
+		}
+
+		if redisTTLOutput == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			encoder.SetEscapeHTML(false)
+			return encoder.Encode(histogram)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "ttl\tkeys\t\n")
+		fmt.Fprintf(w, "none\t%v\t\n", histogram["none"])
+		for _, bucket := range redis.TTLBuckets {
+			fmt.Fprintf(w, "%v\t%v\t\n", bucket.Name, histogram[bucket.Name])
+		}
+		return w.Flush()
+	},
+}
+
+var redisTTLSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Set the TTL of the keys, optionally with a random jitter (PEXPIRE)",
+	Long: `Set the TTL of the keys matching --match, or given by --keys or --keys-file, using PEXPIRE in pipelines of --batch-size keys.
+	With --jitter, a random duration up to it is added to the TTL of each key to spread their expiry, e.g.
+	tkpd redis ttl set -m "session:*" --ttl 720h --jitter 24h --only-persistent`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if redisTTLParam.TTL <= 0 {
+			return errors.New("--ttl must be positive, use persist to remove the TTL")
+		}
+		if redisTTLParam.Jitter < 0 {
+			return errors.New("--jitter must not be negative")
+		}
+		total, err := runTTL(func(client *redis.Client, opts redis.ScanOptions) (int64, error) {
+			return client.Expire(cmd.Context(), opts, redisTTLParam)
+		})
+		log.Printf("set the TTL of %v keys\n", total)
+		return err
+	},
+}
+
+var redisTTLPersistCmd = &cobra.Command{
+	Use:   "persist",
+	Short: "Remove the TTL of the keys (PERSIST)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		total, err := runTTL(func(client *redis.Client, opts redis.ScanOptions) (int64, error) {
+			return client.Persist(cmd.Context(), opts)
+		})
+		log.Printf("removed the TTL of %v keys\n", total)
+		return err
+	},
+}
+
+// runTTL runs fn on each redis node concurrently, it returns the sum of the number of keys returned by fn.
+func runTTL(fn func(client *redis.Client, opts redis.ScanOptions) (int64, error)) (int64, error) {
+	nodes, err := redisNodes()
+	if err != nil {
+		return 0, err
+	}
+	opts := redisScanOptions()
+	keys, err := openKeysFile(&opts)
+	if err != nil {
+		return 0, err
+	}
+	defer keys.Close()
+
+	var total int64
+	err = redis.ForEachNode(nodes, redisParam.concurrency, func(node redis.Config) error {
+		client := redis.NewClient(node)
+		defer client.Close()
+		n, err := fn(client, opts)
+		atomic.AddInt64(&total, n)
+		return err
+	})
+	return total, logScanProgress(err, opts)
+}
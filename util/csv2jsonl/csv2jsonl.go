@@ -0,0 +1,226 @@
+/*
+Package csv2jsonl converts CSV stream in to JSONL stream, the reverse of jsonl2csv.
+*/
+package csv2jsonl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	tkcsv "github.com/keenangebze/go/csv"
+	"github.com/keenangebze/go/pipeline"
+)
+
+// NumOfWorker is the number of goroutine used to process the stream
+var NumOfWorker = 10
+
+// ErrInvalidNumOfWorker thrown if NumOfWorker < 1
+var ErrInvalidNumOfWorker = errors.New("Invalid number of workers. Must be greater than 0.")
+
+// ErrInvalidHeader thrown if the CSV header cannot be mapped in to a JSON object,
+// e.g. duplicated column or both "shop" and "shop.name" exist.
+var ErrInvalidHeader = errors.New("invalid csv header")
+
+// Coercer converts a CSV cell in to a JSON value.
+type Coercer func(cell string) (interface{}, error)
+
+// Built-in coercers. Empty cells are converted to null, except for String.
+var (
+	String Coercer = func(cell string) (interface{}, error) {
+		return cell, nil
+	}
+	Int Coercer = func(cell string) (interface{}, error) {
+		if cell == "" {
+			return nil, nil
+		}
+		return strconv.ParseInt(cell, 10, 64)
+	}
+	Float Coercer = func(cell string) (interface{}, error) {
+		if cell == "" {
+			return nil, nil
+		}
+		return strconv.ParseFloat(cell, 64)
+	}
+	Bool Coercer = func(cell string) (interface{}, error) {
+		if cell == "" {
+			return nil, nil
+		}
+		return strconv.ParseBool(cell)
+	}
+	JSON Coercer = func(cell string) (interface{}, error) {
+		if cell == "" {
+			return nil, nil
+		}
+		if !json.Valid([]byte(cell)) {
+			return nil, fmt.Errorf("invalid json value %q", cell)
+		}
+		return json.RawMessage(cell), nil
+	}
+)
+
+// Coercers maps type name to its Coercer, e.g. to parse the types given from command line.
+var Coercers = map[string]Coercer{
+	"string": String,
+	"int":    Int,
+	"float":  Float,
+	"bool":   Bool,
+	"json":   JSON,
+}
+
+// Csv2Jsonl converts CSV stream with a header in to JSONL stream.
+//
+// The header is used as the keys of the JSON object. Dotted header such as "shop.name" becomes a nested object.
+// types maps a column name to its Coercer, columns without one are kept as string.
+// Rows that cannot be read or coerced are logged and skipped. This will not maintain row ordering.
+func Csv2Jsonl(in io.Reader, out io.Writer, types map[string]Coercer) error {
+	if NumOfWorker < 1 {
+		return ErrInvalidNumOfWorker
+	}
+	return Convert(context.Background(), in, out, types, pipeline.Options{
+		Workers: NumOfWorker,
+		OnError: func(err error) error {
+			log.Printf("Cannot convert %v\n", err)
+			return nil
+		},
+	})
+}
+
+// Convert is like Csv2Jsonl with the pipeline options, e.g. opts.Ordered to maintain row ordering
+// or cancel ctx to stop early. Malformed rows are logged and skipped, the rest of the errors are handled by opts.OnError.
+func Convert(ctx context.Context, in io.Reader, out io.Writer, types map[string]Coercer, opts pipeline.Options) error {
+	csvReader := csv.NewReader(in)
+	header, err := csvReader.Read()
+	if err != nil {
+		return err
+	}
+	fields, err := parseHeader(header, types)
+	if err != nil {
+		return err
+	}
+
+	stage := pipeline.StageFunc[[]string, []byte](func(ctx context.Context, row []string) ([]byte, error) {
+		jsonData, err := toJSON(fields, row)
+		if err != nil {
+			return nil, fmt.Errorf("row %v: %w", row, err)
+		}
+		return jsonData, nil
+	})
+	writer := bufio.NewWriter(out)
+	sink := pipeline.SinkFunc[[]byte](func(ctx context.Context, jsonData []byte) error {
+		writer.Write(jsonData)
+		return writer.WriteByte('\n')
+	})
+
+	err = pipeline.Run[[]string, []byte](ctx, tkcsv.NewRowSource(csvReader), stage, sink, opts)
+	if flushErr := writer.Flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+// field is a CSV column mapped to its path in the JSON object.
+type field struct {
+	column string
+	path   []string
+	coerce Coercer
+}
+
+// parseHeader maps each column in to a field and make sure no column is the parent of another column.
+func parseHeader(header []string, types map[string]Coercer) ([]field, error) {
+	leaves := map[string]bool{}
+	parents := map[string]bool{}
+	fields := make([]field, 0, len(header))
+	for _, column := range header {
+		if leaves[column] || parents[column] {
+			return nil, fmt.Errorf("%w: column %q is duplicated or has nested columns", ErrInvalidHeader, column)
+		}
+		path := strings.Split(column, ".")
+		for i := 1; i < len(path); i++ {
+			parent := strings.Join(path[:i], ".")
+			if leaves[parent] {
+				return nil, fmt.Errorf("%w: column %q is nested under column %q", ErrInvalidHeader, column, parent)
+			}
+			parents[parent] = true
+		}
+		leaves[column] = true
+
+		coerce, ok := types[column]
+		if !ok || coerce == nil {
+			coerce = String
+		}
+		fields = append(fields, field{column: column, path: path, coerce: coerce})
+	}
+	return fields, nil
+}
+
+// toJSON converts a CSV row in to a JSON object.
+func toJSON(fields []field, row []string) ([]byte, error) {
+	obj := newObject()
+	for i, f := range fields {
+		value, err := f.coerce(row[i])
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %w", f.column, err)
+		}
+		obj.set(f.path, value)
+	}
+	return json.Marshal(obj)
+}
+
+// object is a JSON object that keeps the CSV column ordering.
+type object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newObject() *object {
+	return &object{values: map[string]interface{}{}}
+}
+
+// set puts the value in the path, creating the nested objects along the way.
+func (o *object) set(path []string, value interface{}) {
+	for _, key := range path[:len(path)-1] {
+		child, ok := o.values[key]
+		if !ok {
+			child = newObject()
+			o.keys = append(o.keys, key)
+			o.values[key] = child
+		}
+		o = child.(*object)
+	}
+	key := path[len(path)-1]
+	o.keys = append(o.keys, key)
+	o.values[key] = value
+}
+
+// MarshalJSON implements json.Marshaler.
+func (o *object) MarshalJSON() ([]byte, error) {
+	buf := bytes.Buffer{}
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,108 @@
+package csv2jsonl_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/keenangebze/go/util/csv2jsonl"
+)
+
+// Simple use case
+func ExampleCsv2Jsonl() {
+
+	// Emulate a CSV input stream.
+	csvStream := strings.NewReader(`title,publisher.name,publisher.country,year
+Akka in Action,Manning Publications,US,2016
+D3 for the Impatient,O'Reilly Media Inc.,US,2019
+"Designing Data-Intensive Application, 1st Edition",O'Reilly Media Inc.,US,2017`)
+
+	types := map[string]csv2jsonl.Coercer{
+		"year": csv2jsonl.Int,
+	}
+
+	csv2jsonl.Csv2Jsonl(csvStream, os.Stdout, types)
+
+	// Unordered output:
+	//{"title":"Akka in Action","publisher":{"name":"Manning Publications","country":"US"},"year":2016}
+	//{"title":"D3 for the Impatient","publisher":{"name":"O'Reilly Media Inc.","country":"US"},"year":2019}
+	//{"title":"Designing Data-Intensive Application, 1st Edition","publisher":{"name":"O'Reilly Media Inc.","country":"US"},"year":2017}
+}
+
+// TestCoercion asserts every built-in coercer and that empty cells become null.
+func TestCoercion(t *testing.T) {
+	csvStream := strings.NewReader(`id,price,active,tags,note
+1,10.5,true,"[""a"",""b""]",hello
+2,,,,`)
+	out := new(bytes.Buffer)
+
+	types := map[string]csv2jsonl.Coercer{
+		"id":     csv2jsonl.Coercers["int"],
+		"price":  csv2jsonl.Coercers["float"],
+		"active": csv2jsonl.Coercers["bool"],
+		"tags":   csv2jsonl.Coercers["json"],
+	}
+	if err := csv2jsonl.Csv2Jsonl(csvStream, out, types); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]bool{
+		`{"id":1,"price":10.5,"active":true,"tags":["a","b"],"note":"hello"}`: true,
+		`{"id":2,"price":null,"active":null,"tags":null,"note":""}`:           true,
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %v lines, returned %v.\n", len(expected), len(lines))
+	}
+	for _, line := range lines {
+		if !expected[line] {
+			t.Errorf("Unexpected line %v.\n", line)
+		}
+	}
+}
+
+// TestInvalidRowIsSkipped asserts rows that cannot be coerced do not stop the stream.
+func TestInvalidRowIsSkipped(t *testing.T) {
+	csvStream := strings.NewReader(`id,name
+1,Akka in Action
+one,D3 for the Impatient
+3,Designing Data-Intensive Application`)
+	out := new(bytes.Buffer)
+
+	err := csv2jsonl.Csv2Jsonl(csvStream, out, map[string]csv2jsonl.Coercer{"id": csv2jsonl.Int})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := strings.Count(out.String(), "\n"); count != 2 {
+		t.Fatalf("Expected 2 lines, returned %v.\n", count)
+	}
+}
+
+func TestInvalidHeader(t *testing.T) {
+	headers := []string{
+		"id,shop,shop.name",
+		"id,shop.name,shop",
+		"id,name,id",
+	}
+	for _, header := range headers {
+		err := csv2jsonl.Csv2Jsonl(strings.NewReader(header+"\n"), new(bytes.Buffer), nil)
+		if !errors.Is(err, csv2jsonl.ErrInvalidHeader) {
+			t.Errorf("Expected ErrInvalidHeader for header %v, returned %v.\n", header, err)
+		}
+	}
+}
+
+func TestInvalidNumberOfWorkers(t *testing.T) {
+	csvStream := strings.NewReader(`id,name
+1,Akka in Action`)
+
+	defer func(n int) { csv2jsonl.NumOfWorker = n }(csv2jsonl.NumOfWorker)
+	csv2jsonl.NumOfWorker = 0
+
+	err := csv2jsonl.Csv2Jsonl(csvStream, new(bytes.Buffer), nil)
+	if err != csv2jsonl.ErrInvalidNumOfWorker {
+		t.Fail()
+	}
+}
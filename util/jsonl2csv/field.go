@@ -0,0 +1,205 @@
+package jsonl2csv
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidField thrown if a field expression cannot be compiled
+var ErrInvalidField = errors.New("invalid field expression")
+
+// Field is a compiled extraction path to get a CSV cell out of a JSON document.
+//
+// The expression is a dotted path with optional array indexes, e.g. "id", "shop.name", "images[0].url"
+// or `attributes["color.name"]`. Negative index counts from the end of the array.
+// Wrap the path with len() to get the length of an array, object or string, e.g. "len(tags)".
+// A default value for missing or null field can be given after "//", e.g. "shop.name // unknown".
+type Field struct {
+	Expr    string
+	Default string
+	length  bool
+	steps   []step
+}
+
+// step is a single object key or array index lookup in the path.
+type step struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// CompileField compiles the field expression.
+func CompileField(expr string) (Field, error) {
+	field := Field{Expr: expr}
+	path := expr
+	if i := strings.Index(expr, "//"); i >= 0 {
+		path = expr[:i]
+		field.Default = strings.TrimSpace(expr[i+2:])
+	}
+	path = strings.TrimSpace(path)
+	if strings.HasPrefix(path, "len(") && strings.HasSuffix(path, ")") {
+		field.length = true
+		path = strings.TrimSpace(path[4 : len(path)-1])
+	}
+
+	steps, err := parsePath(path)
+	if err != nil {
+		return Field{}, fmt.Errorf("%w %q: %v", ErrInvalidField, expr, err)
+	}
+	field.steps = steps
+	return field, nil
+}
+
+// Fields compiles the field expressions in to a transform function for Jsonl2Csv.
+// Each expression becomes a CSV column in the same order.
+func Fields(exprs ...string) (func(in []byte) ([]string, error), error) {
+	fields := make([]Field, 0, len(exprs))
+	for _, expr := range exprs {
+		field, err := CompileField(expr)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+
+	transform := func(in []byte) ([]string, error) {
+		decoder := json.NewDecoder(bytes.NewReader(in))
+		decoder.UseNumber()
+		var doc interface{}
+		if err := decoder.Decode(&doc); err != nil {
+			return nil, err
+		}
+		row := make([]string, 0, len(fields))
+		for _, field := range fields {
+			row = append(row, field.Extract(doc))
+		}
+		return row, nil
+	}
+	return transform, nil
+}
+
+// Extract gets the field from a decoded JSON document as a CSV cell.
+// Numbers are best decoded as json.Number to keep their original formatting.
+func (f Field) Extract(doc interface{}) string {
+	value, ok := lookup(doc, f.steps)
+	if !ok || value == nil {
+		return f.Default
+	}
+	if f.length {
+		switch v := value.(type) {
+		case []interface{}:
+			return strconv.Itoa(len(v))
+		case map[string]interface{}:
+			return strconv.Itoa(len(v))
+		case string:
+			return strconv.Itoa(utf8.RuneCountInString(v))
+		default:
+			return f.Default
+		}
+	}
+	return toCell(value)
+}
+
+// parsePath parses dotted path in to steps. Empty path or "." refers to the whole document.
+func parsePath(path string) ([]step, error) {
+	rest := strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	steps := []step{}
+	for len(rest) > 0 {
+		switch {
+		case strings.HasPrefix(rest, `["`):
+			quoted, err := strconv.QuotedPrefix(rest[1:])
+			if err != nil || !strings.HasPrefix(rest[1+len(quoted):], "]") {
+				return nil, fmt.Errorf("unterminated quoted key in %q", rest)
+			}
+			key, _ := strconv.Unquote(quoted)
+			steps = append(steps, step{key: key})
+			rest = rest[len(quoted)+2:]
+		case strings.HasPrefix(rest, "["):
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated index in %q", rest)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid index %q", rest[1:end])
+			}
+			steps = append(steps, step{index: index, isIndex: true})
+			rest = rest[end+1:]
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty key in %q", path)
+			}
+			if strings.ContainsAny(rest[:end], "()") {
+				return nil, fmt.Errorf("unexpected parenthesis in %q, use quoted key instead", rest[:end])
+			}
+			steps = append(steps, step{key: rest[:end]})
+			rest = rest[end:]
+		}
+		if strings.HasPrefix(rest, ".") {
+			rest = rest[1:]
+			if rest == "" {
+				return nil, fmt.Errorf("trailing dot in %q", path)
+			}
+		}
+	}
+	return steps, nil
+}
+
+// lookup walks the document following the steps. It returns false if the path does not exist.
+func lookup(doc interface{}, steps []step) (interface{}, bool) {
+	for _, s := range steps {
+		if s.isIndex {
+			array, ok := doc.([]interface{})
+			if !ok {
+				return nil, false
+			}
+			index := s.index
+			if index < 0 {
+				index += len(array)
+			}
+			if index < 0 || index >= len(array) {
+				return nil, false
+			}
+			doc = array[index]
+			continue
+		}
+		object, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		doc, ok = object[s.key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return doc, true
+}
+
+// toCell formats JSON value as CSV cell. Arrays and objects are written as compact JSON.
+func toCell(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+}
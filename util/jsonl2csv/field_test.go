@@ -0,0 +1,71 @@
+package jsonl2csv_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/keenangebze/go/util/jsonl2csv"
+)
+
+// Extract fields without writing a transform function.
+func ExampleFields() {
+
+	// Emulate a JSONL input stream.
+	jsonlStream := strings.NewReader(`{"id": 1, "shop": {"name": "Toko Buku"}, "images": [{"url": "a.jpg"}, {"url": "b.jpg"}], "tags": ["book", "akka"]}
+{"id": 2, "shop": {}, "images": [], "tags": []}`)
+
+	transform, err := jsonl2csv.Fields("id", "shop.name // unknown", "images[0].url", "images[-1].url", "len(tags)")
+	if err != nil {
+		panic(err)
+	}
+
+	jsonl2csv.Jsonl2Csv(jsonlStream, os.Stdout, transform)
+
+	// Unordered output:
+	//1,Toko Buku,a.jpg,b.jpg,2
+	//2,unknown,,,0
+}
+
+func TestFieldExtract(t *testing.T) {
+	transform, err := jsonl2csv.Fields(
+		"$.id",
+		"price",
+		"active",
+		"shop",
+		`attributes["color.name"]`,
+		"variants[1].stock // 0",
+		"len(name)",
+		"len(shop)",
+		"missing.deep[3] // none",
+		"nothing",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	row, err := transform([]byte(`{"id": 12345678901234567890, "price": 1.50, "active": false, "shop": {"id": 1, "name": "A"},
+		"attributes": {"color.name": "red"}, "variants": [{"stock": 3}], "name": "héllo", "nothing": null}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"12345678901234567890", "1.50", "false", `{"id":1,"name":"A"}`, "red", "0", "5", "2", "none", ""}
+	if strings.Join(row, "|") != strings.Join(expected, "|") {
+		t.Fatalf("Expected %v returned %v.\n", expected, row)
+	}
+
+	if _, err := transform([]byte(`{"id": `)); err == nil {
+		t.Fatal("Expected error for malformed json.")
+	}
+}
+
+func TestInvalidField(t *testing.T) {
+	exprs := []string{"images[0", "images[a]", `attributes["color]`, "shop..name", "shop.", "len(tags"}
+	for _, expr := range exprs {
+		_, err := jsonl2csv.CompileField(expr)
+		if !errors.Is(err, jsonl2csv.ErrInvalidField) {
+			t.Errorf("Expected ErrInvalidField for %v, returned %v.\n", expr, err)
+		}
+	}
+}
@@ -1,11 +1,12 @@
 package jsonl2csv
 
 import (
-	"bufio"
+	"context"
 	"encoding/csv"
 	"errors"
 	"io"
-	"sync"
+
+	"github.com/keenangebze/go/pipeline"
 )
 
 // NumOfWorker is the number of goroutine used to process the stream
@@ -16,56 +17,51 @@ var ErrInvalidNumOfWorker = errors.New("Invalid number of workers. Must be great
 
 // Jsonl2Csv Converts JSONL stream in to CSV stream
 func Jsonl2Csv(in io.Reader, out io.Writer, transform func(in []byte) ([]string, error)) error {
-	if NumOfWorker <= 1 {
-		return ErrInvalidNumOfWorker
-	}
-	jsonStream := make(chan []byte)
-	csvStream := make(chan []string)
-	wg1 := sync.WaitGroup{}
-	wg2 := sync.WaitGroup{}
-	wg3 := sync.WaitGroup{}
-	// input stream
-	wg1.Add(1)
-	go func() {
-		lineScanner := bufio.NewScanner(in)
-		lineScanner.Split(bufio.ScanLines)
-		for lineScanner.Scan() {
-			jsonStream <- lineScanner.Bytes()
-		}
-		wg1.Done()
-	}()
+	return Json2Csv(in, out, SplitLines, transform)
+}
 
-	// jsonl2csv converter
-	for i := 0; i < NumOfWorker; i++ {
-		wg2.Add(1)
-		go func() {
-			for m := range jsonStream {
-				csvData, err := transform(m)
-				if err != nil {
-					continue
-				}
-				csvStream <- csvData
-			}
-			wg2.Done()
-		}()
+// Json2Csv is like Jsonl2Csv but the JSON documents are split from the input stream using split,
+// e.g. SplitJSON("hits.hits") for ES scroll responses.
+// It returns the error from split if the input stream cannot be read until the end.
+func Json2Csv(in io.Reader, out io.Writer, split Splitter, transform func(in []byte) ([]string, error)) error {
+	csvWriter := csv.NewWriter(out)
+	err := Json2Rows(in, csvWriter, split, transform)
+	csvWriter.Flush()
+	if err != nil {
+		return err
 	}
+	return csvWriter.Error()
+}
 
-	// output stream
-	wg3.Add(1)
-	go func() {
-		out := csv.NewWriter(out)
-		for m := range csvStream {
-			out.Write(m)
-			out.Flush()
-		}
-		wg3.Done()
-	}()
+// RowWriter is the output sink of the converted rows, e.g. *csv.Writer or *parquet.Writer.
+type RowWriter interface {
+	Write(row []string) error
+}
 
-	wg1.Wait()
-	close(jsonStream)
-	wg2.Wait()
-	close(csvStream)
-	wg3.Wait()
+// Json2Rows is like Json2Csv but the rows are written to out, e.g. a parquet.Writer.
+// The caller is responsible to flush or close out. It returns the first error from split or out.
+func Json2Rows(in io.Reader, out RowWriter, split Splitter, transform func(in []byte) ([]string, error)) error {
+	if NumOfWorker < 1 {
+		return ErrInvalidNumOfWorker
+	}
+	return Convert(context.Background(), in, out, split, transform, pipeline.Options{
+		Workers: NumOfWorker,
+		OnError: pipeline.SkipErrors,
+	})
+}
 
-	return nil
+// Convert is like Json2Rows with the pipeline options, e.g. opts.Ordered to maintain the document ordering
+// or cancel ctx to stop early. Set opts.OnError to pipeline.SkipErrors to skip the documents that transform fails on
+// and the rows out rejects.
+func Convert(ctx context.Context, in io.Reader, out RowWriter, split Splitter, transform func(in []byte) ([]string, error), opts pipeline.Options) error {
+	source := pipeline.FromEmitter(func(emit func([]byte) error) error {
+		return split(in, emit)
+	})
+	stage := pipeline.StageFunc[[]byte, []string](func(ctx context.Context, doc []byte) ([]string, error) {
+		return transform(doc)
+	})
+	sink := pipeline.SinkFunc[[]string](func(ctx context.Context, row []string) error {
+		return out.Write(row)
+	})
+	return pipeline.Run[[]byte, []string](ctx, source, stage, sink, opts)
 }
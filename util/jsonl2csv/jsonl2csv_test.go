@@ -103,6 +103,8 @@ func TestInvalidNumberOfWorkers(t *testing.T) {
 		return result, nil
 	}
 
+	// restore the default, the examples of the package run after the tests
+	defer func(n int) { jsonl2csv.NumOfWorker = n }(jsonl2csv.NumOfWorker)
 	jsonl2csv.NumOfWorker = -1
 
 	err := jsonl2csv.Jsonl2Csv(jsonlStream, os.Stdout, toBookCsv)
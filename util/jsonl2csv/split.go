@@ -0,0 +1,150 @@
+package jsonl2csv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// MaxLineSize is the maximum size of a single line read by SplitLines.
+var MaxLineSize = 64 * 1024 * 1024
+
+// Splitter reads the input stream and calls emit for each JSON document found.
+// The emitted slice is owned by the receiver and will not be reused by the Splitter.
+// The Splitter stops and returns the error once emit returns an error.
+type Splitter func(in io.Reader, emit func(doc []byte) error) error
+
+// SplitLines splits the input stream by line, each line is a JSON document (JSONL).
+func SplitLines(in io.Reader, emit func(doc []byte) error) error {
+	lineScanner := bufio.NewScanner(in)
+	lineScanner.Buffer(nil, MaxLineSize)
+	lineScanner.Split(bufio.ScanLines)
+	for lineScanner.Scan() {
+		// protect against the scanner reusing its buffer
+		line := make([]byte, len(lineScanner.Bytes()))
+		copy(line, lineScanner.Bytes())
+		if err := emit(line); err != nil {
+			return err
+		}
+	}
+	return lineScanner.Err()
+}
+
+// SplitJSON returns a Splitter that streams JSON documents without loading the whole input in to memory.
+//
+// The input may be a single JSON array (`[{...},{...}]`), concatenated JSON values with or without
+// newlines between them, or a mix of both. Each object is a document and each array is streamed element by element.
+// path selects where the documents are inside each top-level value, e.g. "hits.hits" for ES scroll responses
+// or "responses[0].hits.hits" for msearch. Values outside the path are skipped.
+func SplitJSON(path string) (Splitter, error) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %v", ErrInvalidField, path, err)
+	}
+	split := func(in io.Reader, emit func(doc []byte) error) error {
+		decoder := json.NewDecoder(in)
+		for {
+			err := walk(decoder, steps, emit)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return split, nil
+}
+
+// walk reads the next JSON value and emits the documents found in the path.
+func walk(decoder *json.Decoder, steps []step, emit func(doc []byte) error) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	switch token {
+	case json.Delim('{'):
+		if len(steps) == 0 {
+			doc, err := readObject(decoder)
+			if err != nil {
+				return err
+			}
+			if err := emit(doc); err != nil {
+				return err
+			}
+			break
+		}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			if steps[0].isIndex || key != steps[0].key {
+				err = skip(decoder)
+			} else {
+				err = walk(decoder, steps[1:], emit)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	case json.Delim('['):
+		for i := 0; decoder.More(); i++ {
+			var err error
+			switch {
+			case len(steps) == 0:
+				var doc json.RawMessage
+				if err = decoder.Decode(&doc); err == nil {
+					err = emit(doc)
+				}
+			case steps[0].isIndex && steps[0].index == i:
+				err = walk(decoder, steps[1:], emit)
+			default:
+				err = skip(decoder)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		// scalar is not a document, nothing to emit
+		return nil
+	}
+	// the closing delimiter
+	_, err = decoder.Token()
+	return err
+}
+
+// readObject reads the rest of an object whose opening brace is already consumed.
+// The closing brace is left for the caller.
+func readObject(decoder *json.Decoder) ([]byte, error) {
+	buf := bytes.Buffer{}
+	buf.WriteByte('{')
+	for i := 0; decoder.More(); i++ {
+		key, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		k, _ := json.Marshal(key)
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// skip reads and discards the next JSON value.
+func skip(decoder *json.Decoder) error {
+	var value json.RawMessage
+	return decoder.Decode(&value)
+}
@@ -0,0 +1,94 @@
+package jsonl2csv_test
+
+import (
+	"bytes"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/keenangebze/go/util/jsonl2csv"
+)
+
+// Convert ES scroll responses without extracting the hits first.
+func ExampleSplitJSON() {
+
+	// Emulate two concatenated ES scroll responses.
+	scrollStream := strings.NewReader(`{"_scroll_id": "abc", "hits": {"total": 3, "hits": [
+	{"_id": "1", "_source": {"title": "Akka in Action"}},
+	{"_id": "2", "_source": {"title": "D3 for the Impatient"}}]}}{"_scroll_id": "abc", "hits": {"total": 3, "hits": [
+	{"_id": "3", "_source": {"title": "Designing Data-Intensive Application"}}]}}`)
+
+	split, err := jsonl2csv.SplitJSON("hits.hits")
+	if err != nil {
+		panic(err)
+	}
+	transform, err := jsonl2csv.Fields("_id", "_source.title")
+	if err != nil {
+		panic(err)
+	}
+
+	jsonl2csv.Json2Csv(scrollStream, os.Stdout, split, transform)
+
+	// Unordered output:
+	//1,Akka in Action
+	//2,D3 for the Impatient
+	//3,Designing Data-Intensive Application
+}
+
+func TestSplitJSON(t *testing.T) {
+	cases := []struct {
+		name     string
+		path     string
+		input    string
+		expected []string
+	}{
+		{"array", "", `[{"id": 1}, {"id": 2}]`, []string{`{"id": 1}`, `{"id": 2}`}},
+		{"concatenated", "", `{"id": 1}{"id":2} {"id": 3}` + "\n" + `[{"id": 4}]`, []string{`{"id":1}`, `{"id":2}`, `{"id":3}`, `{"id": 4}`}},
+		{"nested object", "", `{"id": 1, "shop": {"name": "A, B"}, "tags": ["x"]}`, []string{`{"id":1,"shop":{"name": "A, B"},"tags":["x"]}`}},
+		{"path", "data.items", `{"meta": {"items": [{"id": 0}]}, "data": {"items": [{"id": 1}, {"id": 2}]}}`, []string{`{"id": 1}`, `{"id": 2}`}},
+		{"path to object", "data", `{"data": {"id": 1}}`, []string{`{"id":1}`}},
+		{"path with index", "responses[1].hits", `{"responses": [{"hits": [{"id": 0}]}, {"hits": [{"id": 1}]}]}`, []string{`{"id": 1}`}},
+		{"missing path", "hits.hits", `{"error": "timeout"}`, nil},
+	}
+	for _, c := range cases {
+		split, err := jsonl2csv.SplitJSON(c.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actual []string
+		err = split(strings.NewReader(c.input), func(doc []byte) error {
+			actual = append(actual, string(doc))
+			return nil
+		})
+		if err != nil {
+			t.Errorf("%v: unexpected error %v.\n", c.name, err)
+		}
+		if strings.Join(actual, "\n") != strings.Join(c.expected, "\n") {
+			t.Errorf("%v: expected %v returned %v.\n", c.name, c.expected, actual)
+		}
+	}
+}
+
+// TestSplitJSONCorrupted asserts the error is returned and the documents before it are still converted.
+func TestSplitJSONCorrupted(t *testing.T) {
+	split, err := jsonl2csv.SplitJSON("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transform, err := jsonl2csv.Fields("id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+
+	err = jsonl2csv.Json2Csv(strings.NewReader(`[{"id": 1}, {"id": 2}, {"id": `), out, split, transform)
+	if err == nil {
+		t.Fatal("Expected error for corrupted stream.")
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	sort.Strings(lines)
+	if strings.Join(lines, ",") != "1,2" {
+		t.Fatalf("Expected 1 and 2 returned %v.\n", lines)
+	}
+}
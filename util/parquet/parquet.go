@@ -0,0 +1,304 @@
+/*
+Package parquet writes CSV-like rows as Parquet file, an alternative output sink for the csv and jsonl2csv pipelines.
+*/
+package parquet
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	pq "github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// Type is the Parquet type of a column.
+type Type string
+
+// Supported column types. Infer lets the Writer choose the type from the first rows.
+const (
+	Infer   Type = ""
+	String  Type = "string"
+	Int64   Type = "int64"
+	Double  Type = "double"
+	Boolean Type = "boolean"
+)
+
+// Column is a column in the Parquet schema.
+type Column struct {
+	Name string
+	Type Type
+}
+
+// Columns is a schema with the given column names, the types are inferred.
+func Columns(names ...string) []Column {
+	schema := make([]Column, 0, len(names))
+	for _, name := range names {
+		schema = append(schema, Column{Name: name})
+	}
+	return schema
+}
+
+// Compressions maps compression name to its codec.
+var Compressions = map[string]pq.CompressionCodec{
+	"none":   pq.CompressionCodec_UNCOMPRESSED,
+	"snappy": pq.CompressionCodec_SNAPPY,
+	"gzip":   pq.CompressionCodec_GZIP,
+	"zstd":   pq.CompressionCodec_ZSTD,
+}
+
+// Options configures the Parquet file layout. Zero values use the defaults.
+type Options struct {
+	// Compression is one of the Compressions, default to snappy.
+	Compression string
+	// RowGroupSize is the approximate size of a row group in bytes, default to 128MB.
+	RowGroupSize int64
+	// PageSize is the approximate size of a page in bytes, default to 8KB.
+	PageSize int64
+	// InferRows is the number of rows buffered to infer the schema, default to 1000.
+	InferRows int
+}
+
+// ErrRowLength thrown if a row has different number of cells than the schema.
+var ErrRowLength = errors.New("row length does not match the schema")
+
+// ErrUnknownCompression thrown if Options.Compression is not one of the Compressions.
+var ErrUnknownCompression = errors.New("unknown compression")
+
+// Writer writes rows as Parquet file. Empty cells are written as null, except in string columns.
+//
+// If the schema is nil or has columns without type, the first Options.InferRows rows are buffered
+// to infer the column types. Later rows that cannot be parsed as the inferred type are rejected.
+// Close must be called to write the Parquet footer.
+type Writer struct {
+	out         io.Writer
+	schema      []Column
+	opts        Options
+	compression pq.CompressionCodec
+	buffer      [][]string
+	pw          *writer.CSVWriter
+}
+
+// NewWriter instantiates the Parquet writer.
+func NewWriter(out io.Writer, schema []Column, opts Options) (*Writer, error) {
+	if opts.Compression == "" {
+		opts.Compression = "snappy"
+	}
+	compression, ok := Compressions[opts.Compression]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownCompression, opts.Compression)
+	}
+	if opts.InferRows <= 0 {
+		opts.InferRows = 1000
+	}
+	w := Writer{
+		out:         out,
+		schema:      schema,
+		opts:        opts,
+		compression: compression,
+	}
+	if schema != nil && !needInference(schema) {
+		if err := w.start(); err != nil {
+			return nil, err
+		}
+	}
+	return &w, nil
+}
+
+// Schema returns the schema of the file, it may be incomplete until the types are inferred.
+func (w *Writer) Schema() []Column {
+	return w.schema
+}
+
+// Write writes a row.
+func (w *Writer) Write(row []string) error {
+	if w.schema != nil && len(row) != len(w.schema) {
+		return fmt.Errorf("%w: expected %v cells returned %v", ErrRowLength, len(w.schema), len(row))
+	}
+	if w.pw != nil {
+		return w.write(row)
+	}
+
+	// protect against shared row reference
+	copiedRow := make([]string, len(row))
+	copy(copiedRow, row)
+	if w.schema == nil {
+		w.schema = make([]Column, len(row))
+	}
+	w.buffer = append(w.buffer, copiedRow)
+	if len(w.buffer) < w.opts.InferRows {
+		return nil
+	}
+	return w.flushBuffer()
+}
+
+// Close writes the buffered rows and the Parquet footer. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.schema == nil {
+		// nothing was written and there is no schema to write
+		return nil
+	}
+	if w.pw == nil {
+		if err := w.flushBuffer(); err != nil {
+			return err
+		}
+	}
+	return w.pw.WriteStop()
+}
+
+// flushBuffer infers the schema from the buffered rows, then writes them.
+func (w *Writer) flushBuffer() error {
+	w.schema = inferSchema(w.schema, w.buffer)
+	if err := w.start(); err != nil {
+		return err
+	}
+	for _, row := range w.buffer {
+		if err := w.write(row); err != nil {
+			return err
+		}
+	}
+	w.buffer = nil
+	return nil
+}
+
+// start creates the underlying Parquet writer once the schema is complete.
+func (w *Writer) start() error {
+	metadata := make([]string, 0, len(w.schema))
+	names := sanitizeNames(w.schema)
+	for i, column := range w.schema {
+		name := names[i]
+		switch column.Type {
+		case String:
+			metadata = append(metadata, "name="+name+", type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL")
+		case Int64:
+			metadata = append(metadata, "name="+name+", type=INT64, repetitiontype=OPTIONAL")
+		case Double:
+			metadata = append(metadata, "name="+name+", type=DOUBLE, repetitiontype=OPTIONAL")
+		case Boolean:
+			metadata = append(metadata, "name="+name+", type=BOOLEAN, repetitiontype=OPTIONAL")
+		default:
+			return fmt.Errorf("unknown type %q for column %q", column.Type, column.Name)
+		}
+	}
+
+	pw, err := writer.NewCSVWriterFromWriter(metadata, w.out, 1)
+	if err != nil {
+		return err
+	}
+	pw.CompressionType = w.compression
+	if w.opts.RowGroupSize > 0 {
+		pw.RowGroupSize = w.opts.RowGroupSize
+	}
+	if w.opts.PageSize > 0 {
+		pw.PageSize = w.opts.PageSize
+	}
+	w.pw = pw
+	return nil
+}
+
+// write parses the cells according to the column types and writes them. Empty cells are written as null.
+func (w *Writer) write(row []string) error {
+	values := make([]interface{}, len(row))
+	for i, cell := range row {
+		var err error
+		switch column := w.schema[i]; {
+		case column.Type == String:
+			values[i] = cell
+		case cell == "":
+			values[i] = nil
+		case column.Type == Int64:
+			values[i], err = strconv.ParseInt(cell, 10, 64)
+		case column.Type == Double:
+			values[i], err = strconv.ParseFloat(cell, 64)
+		case column.Type == Boolean:
+			values[i], err = strconv.ParseBool(strings.ToLower(cell))
+		}
+		if err != nil {
+			return fmt.Errorf("cannot write %q to %v column %q: %w", cell, w.schema[i].Type, w.schema[i].Name, err)
+		}
+	}
+	return w.pw.Write(values)
+}
+
+// needInference checks whether any column has no type.
+func needInference(schema []Column) bool {
+	for _, column := range schema {
+		if column.Type == Infer {
+			return true
+		}
+	}
+	return false
+}
+
+// inferSchema chooses the narrowest type that can parse every non empty cell of the column.
+// Columns without any value are inferred as string.
+func inferSchema(schema []Column, rows [][]string) []Column {
+	inferred := make([]Column, len(schema))
+	copy(inferred, schema)
+	for i := range inferred {
+		if inferred[i].Type != Infer {
+			continue
+		}
+		seen, isBoolean, isInt64, isDouble := false, true, true, true
+		for _, row := range rows {
+			cell := row[i]
+			if cell == "" {
+				continue
+			}
+			seen = true
+			lower := strings.ToLower(cell)
+			isBoolean = isBoolean && (lower == "true" || lower == "false")
+			if isInt64 {
+				_, err := strconv.ParseInt(cell, 10, 64)
+				isInt64 = err == nil
+			}
+			if isDouble {
+				_, err := strconv.ParseFloat(cell, 64)
+				isDouble = err == nil
+			}
+		}
+		switch {
+		case !seen:
+			inferred[i].Type = String
+		case isBoolean:
+			inferred[i].Type = Boolean
+		case isInt64:
+			inferred[i].Type = Int64
+		case isDouble:
+			inferred[i].Type = Double
+		default:
+			inferred[i].Type = String
+		}
+	}
+	return inferred
+}
+
+var invalidNameChars = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// sanitizeNames sanitizes the column names, numbering the names already taken by a previous column
+// from 2, e.g. "images[0].url" and "images_0_url" become "images_0_url" and "images_0_url_2".
+func sanitizeNames(schema []Column) []string {
+	names := make([]string, len(schema))
+	taken := make(map[string]bool, len(schema))
+	for i, column := range schema {
+		name := sanitizeName(column.Name, i)
+		for n := 2; taken[name]; n++ {
+			name = sanitizeName(column.Name, i) + "_" + strconv.Itoa(n)
+		}
+		taken[name] = true
+		names[i] = name
+	}
+	return names
+}
+
+// sanitizeName makes the column name safe for the Parquet schema, e.g. "images[0].url" becomes "images_0_url".
+func sanitizeName(name string, index int) string {
+	name = strings.Trim(invalidNameChars.ReplaceAllString(name, "_"), "_")
+	if name == "" {
+		return "col_" + strconv.Itoa(index+1)
+	}
+	return name
+}
@@ -0,0 +1,197 @@
+package parquet_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+
+	"github.com/keenangebze/go/csv"
+	"github.com/keenangebze/go/util/jsonl2csv"
+	"github.com/keenangebze/go/util/parquet"
+)
+
+// readBack reads the Parquet file and returns the column names and the rows formatted with %v.
+func readBack(t *testing.T, data []byte) ([]string, []string) {
+	t.Helper()
+	file, err := buffer.NewBufferFile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr, err := reader.NewParquetColumnReader(file, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.ReadStop()
+
+	numRows := pr.GetNumRows()
+	var names []string
+	var columns [][]interface{}
+	for i, info := range pr.SchemaHandler.Infos[1:] {
+		names = append(names, info.ExName)
+		values, _, _, err := pr.ReadColumnByIndex(int64(i), numRows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		columns = append(columns, values)
+	}
+
+	rows := make([]string, numRows)
+	for r := range rows {
+		cells := make([]string, len(columns))
+		for c := range columns {
+			cells[c] = fmt.Sprint(columns[c][r])
+		}
+		rows[r] = strings.Join(cells, ",")
+	}
+	return names, rows
+}
+
+func TestWriterInferSchema(t *testing.T) {
+	out := new(bytes.Buffer)
+	w, err := parquet.NewWriter(out, parquet.Columns("id", "price", "active", "title", "images[0].url"), parquet.Options{Compression: "zstd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := [][]string{
+		{"1", "10.5", "true", "Akka in Action", ""},
+		{"2", "", "FALSE", "", ""},
+		{"3", "7", "", "D3 for the Impatient", ""},
+	}
+	for _, row := range rows {
+		if err := w.Write(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedTypes := []parquet.Type{parquet.Int64, parquet.Double, parquet.Boolean, parquet.String, parquet.String}
+	for i, column := range w.Schema() {
+		if column.Type != expectedTypes[i] {
+			t.Errorf("Expected column %v as %v returned %v.\n", column.Name, expectedTypes[i], column.Type)
+		}
+	}
+
+	names, actual := readBack(t, out.Bytes())
+	if strings.Join(names, ",") != "id,price,active,title,images_0_url" {
+		t.Errorf("Unexpected column names %v.\n", names)
+	}
+	expected := []string{
+		"1,10.5,true,Akka in Action,",
+		"2,<nil>,false,,",
+		"3,7,<nil>,D3 for the Impatient,",
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Expected %v returned %v.\n", expected, actual)
+	}
+}
+
+// TestWriterSchema asserts the given schema is used as is, rows not matching it are rejected.
+func TestWriterSchema(t *testing.T) {
+	out := new(bytes.Buffer)
+	schema := []parquet.Column{{Name: "id", Type: parquet.String}, {Name: "stock", Type: parquet.Int64}}
+	w, err := parquet.NewWriter(out, schema, parquet.Options{Compression: "none", RowGroupSize: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write([]string{"001", "5"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write([]string{"002", "five"}); err == nil {
+		t.Error("Expected error for invalid int64.")
+	}
+	if err := w.Write([]string{"003"}); !errors.Is(err, parquet.ErrRowLength) {
+		t.Errorf("Expected ErrRowLength returned %v.\n", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, actual := readBack(t, out.Bytes())
+	if strings.Join(actual, "\n") != "001,5" {
+		t.Fatalf("Unexpected rows %v.\n", actual)
+	}
+}
+
+// TestWriterDuplicateNames asserts the columns sanitized to the same name are suffixed.
+func TestWriterDuplicateNames(t *testing.T) {
+	out := new(bytes.Buffer)
+	w, err := parquet.NewWriter(out, parquet.Columns("images[0].url", "images_0_url", "images.0.url"), parquet.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write([]string{"a", "b", "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	names, rows := readBack(t, out.Bytes())
+	if fmt.Sprint(names) != "[images_0_url images_0_url_2 images_0_url_3]" || fmt.Sprint(rows) != "[a,b,c]" {
+		t.Fatalf("Expected the duplicate names suffixed returned %v %v.\n", names, rows)
+	}
+}
+
+func TestUnknownCompression(t *testing.T) {
+	_, err := parquet.NewWriter(new(bytes.Buffer), nil, parquet.Options{Compression: "brotli"})
+	if !errors.Is(err, parquet.ErrUnknownCompression) {
+		t.Fatalf("Expected ErrUnknownCompression returned %v.\n", err)
+	}
+}
+
+func TestJsonl2Parquet(t *testing.T) {
+	jsonlStream := strings.NewReader(`{"id": 1, "shop": {"name": "Toko Buku"}}
+{"id": 2, "shop": {"name": "Toko Komik"}}`)
+	fields := []string{"id", "shop.name"}
+	transform, err := jsonl2csv.Fields(fields...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	w, err := parquet.NewWriter(out, parquet.Columns(fields...), parquet.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := jsonl2csv.Json2Rows(jsonlStream, w, jsonl2csv.SplitLines, transform); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, actual := readBack(t, out.Bytes())
+	sort.Strings(actual)
+	if strings.Join(actual, "\n") != "1,Toko Buku\n2,Toko Komik" {
+		t.Fatalf("Unexpected rows %v.\n", actual)
+	}
+}
+
+func TestCSV2Parquet(t *testing.T) {
+	csvStream := strings.NewReader(`a,b
+1,2
+3,4`)
+	out := new(bytes.Buffer)
+	w, err := parquet.NewWriter(out, nil, parquet.Options{Compression: "gzip"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csv.ProcessCSVByRowParallelTo(csvStream, w, func(row []string) []string { return row }, true)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	names, actual := readBack(t, out.Bytes())
+	sort.Strings(actual)
+	if strings.Join(names, ",") != "col_1,col_2" || strings.Join(actual, "\n") != "1,2\n3,4" {
+		t.Fatalf("Unexpected file %v %v.\n", names, actual)
+	}
+}